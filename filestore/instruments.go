@@ -0,0 +1,69 @@
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/crockeo/go-tuner/synth"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Reading a JSON list of synth.InstrumentDefinitions and registering each of
+// them.
+func ReadInstruments(reader io.Reader) error {
+	defs := []synth.InstrumentDefinition{}
+	if err := json.NewDecoder(reader).Decode(&defs); err != nil {
+		return err
+	}
+
+	for _, def := range defs {
+		if err := def.Register(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Loading instrument definitions from a JSON file, or from every JSON file in
+// a directory.
+func LoadInstruments(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{path}
+	if info.IsDir() {
+		paths = []string{}
+
+		infos, err := ioutil.ReadDir(path)
+		if err != nil {
+			return err
+		}
+
+		for _, fi := range infos {
+			if !fi.IsDir() && filepath.Ext(fi.Name()) == ".json" {
+				paths = append(paths, filepath.Join(path, fi.Name()))
+			}
+		}
+	}
+
+	for _, p := range paths {
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+
+		err = ReadInstruments(file)
+		file.Close()
+
+		if err != nil {
+			return errors.New("Could not load instruments from \"" + p + "\": " + err.Error())
+		}
+	}
+
+	return nil
+}
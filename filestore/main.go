@@ -9,6 +9,12 @@ import (
 
 // Loading a synth.NoteArrangement form a file on disk.
 func LoadNoteArrangement(path string) (*synth.NoteArrangement, error) {
+	return LoadNoteArrangementWith(path, synth.DefaultPlaybackOptions())
+}
+
+// Loading a synth.NoteArrangement from a file on disk, changing the timing and
+// pitch of every note by a set of synth.PlaybackOptions.
+func LoadNoteArrangementWith(path string, options synth.PlaybackOptions) (*synth.NoteArrangement, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, errors.New("Could not open \"" + path + "\".")
@@ -24,7 +30,7 @@ func LoadNoteArrangement(path string) (*synth.NoteArrangement, error) {
 		return nil, err
 	}
 
-	na, err := synth.MakeNoteArrangement(rdnds)
+	na, err := synth.MakeNoteArrangementWith(rdnds, options)
 	if err != nil {
 		return nil, err
 	}
@@ -2,134 +2,220 @@ package filestore
 
 import (
 	"errors"
+	"fmt"
 	"github.com/crockeo/go-tuner/filestore/midi"
 	"github.com/crockeo/go-tuner/synth"
 	"io"
+	"math"
+	"sort"
 )
 
-// Converting a tick amount to a real time delay.
-func convertTick(division int16, delay uint) float32 {
-	switch division >> 15 {
-	case 0:
-		return 0.5 * (float32(delay) / float32(division&0x7FFF))
-	case 1:
-		panic("convertTick case 1 - need to be implemented.")
+const (
+	defaultTempo  uint32 = 500000 // The tempo before any Set Tempo event, 120 BPM.
+	panController uint8  = 10     // The controller number of MIDI Pan.
+	writeDivision int16  = 480    // The ticks per quarter note of written files.
+	writeTempo    uint32 = 500000 // The microseconds per quarter note of written files.
+
+	// MIDI, and Scala with it, puts every note an octave above where
+	// synth.NoteToInt does, so that A4 is 69.
+	midiNoteOffset int = 12
+
+	// The instrument notes are played on when their track isn't named after
+	// one.
+	defaultInstrument string = "guitar"
+)
+
+// Converting a tick amount at a given tempo, in microseconds per quarter note,
+// to a real time delay.
+//
+// When the top bit of the division is set the file uses SMPTE timing instead:
+// the upper byte is the negated frames per second and the lower byte the ticks
+// per frame, and the tempo doesn't apply.
+func convertTick(division int16, tempo uint32, delay uint) (float32, error) {
+	if division >= 0 {
+		if division == 0 {
+			return 0, errors.New("Invalid MIDI division: 0 ticks per quarter note.")
+		}
+
+		return float32(float64(tempo) / 1000000 * float64(delay) / float64(division)), nil
+	}
+
+	var fps float64
+	switch -int8(division >> 8) {
+	case 24:
+		fps = 24
+	case 25:
+		fps = 25
+	case 29:
+		fps = 30000.0 / 1001.0
+	case 30:
+		fps = 30
 	default:
-		panic("convertTick default select - you shouldn't be able to get here.")
+		return 0, errors.New(fmt.Sprintf("Unsupported SMPTE format: %d frames per second.", -int8(division>>8)))
+	}
+
+	ticksPerFrame := division & 0xFF
+	if ticksPerFrame == 0 {
+		return 0, errors.New("Invalid MIDI division: 0 ticks per frame.")
 	}
 
-	return 0.0
+	return float32(float64(delay) / (fps * float64(ticksPerFrame))), nil
 }
 
-// Constructing a single []synth.RawDelayedNoteData from a MIDI track.
-func constructNoteArrangement(header midi.Header, track midi.Track) []synth.RawDelayedNoteData {
-	rdnds := []synth.RawDelayedNoteData{}
+// A change of tempo at an absolute tick.
+type tempoChange struct {
+	tick  uint
+	tempo uint32
+}
 
-	for i := 0; i < len(track); i++ {
-		t1 := track[i]
+// Type tempoMap converts absolute ticks into seconds, taking every tempo change
+// before a tick into account.
+type tempoMap struct {
+	division int16
+	changes  []tempoChange
+}
 
-		var diff uint = 0
-		for j := i + 1; j < len(track); j++ {
-			diff += track[j].Delay
-			if !track[j].Switch && track[j].Channel == t1.Channel && track[j].Key == t1.Key {
-				break
+// Building a tempo map from the Set Tempo events in a set of tracks.
+func makeTempoMap(division int16, tracks []midi.Track) tempoMap {
+	changes := []tempoChange{}
+	for _, track := range tracks {
+		var tick uint = 0
+		for _, e := range track {
+			tick += e.Delay
+			if e.Kind == midi.TempoEvent {
+				changes = append(changes, tempoChange{tick, e.Tempo})
 			}
 		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].tick < changes[j].tick
+	})
+
+	return tempoMap{division, changes}
+}
+
+// Converting an absolute tick into a time in seconds.
+func (tm tempoMap) seconds(tick uint) (float32, error) {
+	var time float32 = 0
+	var last uint = 0
+	tempo := defaultTempo
+
+	for _, c := range tm.changes {
+		if c.tick >= tick {
+			break
+		}
 
-		// TODO: Non-fixed note duration.
+		t, err := convertTick(tm.division, tempo, c.tick-last)
+		if err != nil {
+			return 0, err
+		}
 
-		delay := convertTick(header.Division, t1.Delay)
-		rdnds = append(rdnds, synth.RawDelayedNoteData{
-			delay,
-			synth.NoteToString(int(t1.Key)),
-			0.7, //(convertTick(header.Division, diff) - delay) + 0.25,
-			"guitar",
-		})
+		time += t
+		last = c.tick
+		tempo = c.tempo
 	}
 
-	return rdnds
+	t, err := convertTick(tm.division, tempo, tick-last)
+	if err != nil {
+		return 0, err
+	}
+
+	return time + t, nil
 }
 
-// Merging two []synth.RawDelayedNoteDatas together.
-func mergeNoteArrangements(na1 []synth.RawDelayedNoteData, na2 []synth.RawDelayedNoteData) []synth.RawDelayedNoteData {
-	accum := []synth.RawDelayedNoteData{}
-	offset := float32(0.0)
-	side := 0
-
-	for len(na1) > 0 || len(na2) > 0 {
-		// There are no more notes from na1 to append, so appending everything
-		// that is left from na2.
-		if len(na1) == 0 {
-			t := na2[0]
-			if side == 1 {
-				t.Delay -= offset
-
-				offset = 0.0
-				side = 0
-			}
+// A note along with the absolute time, in seconds, that it starts at.
+type timedNote struct {
+	start float32
+	rdnd  synth.RawDelayedNoteData
+}
 
-			accum = append(accum, t)
-			na2 = na2[1:]
+// Constructing the notes in a single MIDI track. Each note on is paired with
+// the next note off on the same channel and key, so overlapping notes on one
+// key are ended in the order they were started. Notes are played on the
+// instrument the track is named after, or a guitar when there's no such
+// instrument.
+func constructNotes(tm tempoMap, track midi.Track) ([]timedNote, error) {
+	notes := []timedNote{}
+	playing := map[[2]uint8][]int{}
+	pans := map[uint8]float32{}
+	instrument := defaultInstrument
 
-			continue
+	var tick uint = 0
+	for _, e := range track {
+		tick += e.Delay
+		if e.Kind == midi.ControlEvent && e.Controller == panController {
+			pans[e.Channel] = valueToPan(e.Value)
 		}
 
-		// There are no more notes from na2 to append, so appending everything
-		// that is left from na1.
-		if len(na2) == 0 {
-			t := na1[0]
-			if side == 2 {
-				t.Delay -= offset
-
-				offset = 0.0
-				side = 0
+		if e.Kind == midi.NameEvent {
+			instrument = defaultInstrument
+			if _, ok := synth.GetInstrument(e.Name); ok {
+				instrument = e.Name
 			}
+		}
 
-			accum = append(accum, t)
-			na1 = na1[1:]
-
+		if e.Kind != midi.NoteEvent {
 			continue
 		}
 
-		// Appending whichever note is going to happen soonest.
-		if side == 0 {
-			if na1[0].Delay < na2[0].Delay {
-				accum = append(accum, na1[0])
-				offset = na1[0].Delay
-				na1 = na1[1:]
-				side = 1
-			} else {
-				accum = append(accum, na2[0])
-				offset = na2[0].Delay
-				na2 = na2[1:]
-				side = 2
-			}
-		} else if side == 1 {
-			if na1[0].Delay < na2[0].Delay-offset {
-				accum = append(accum, na1[0])
-				offset += na1[0].Delay
-				na1 = na1[1:]
-			} else {
-				accum = append(accum, na2[0])
-				offset = na2[0].Delay
-				na2 = na2[1:]
-				side = 2
-			}
-		} else if side == 2 {
-			if na1[0].Delay-offset < na2[0].Delay {
-				accum = append(accum, na1[0])
-				offset = na1[0].Delay
-				na1 = na1[1:]
-				side = 1
-			} else {
-				accum = append(accum, na2[0])
-				offset += na2[0].Delay
-				na2 = na2[1:]
-			}
+		time, err := tm.seconds(tick)
+		if err != nil {
+			return nil, err
+		}
+
+		// A note on with no velocity is a note off, as midi.Read reads them.
+		key := [2]uint8{e.Channel, e.Key}
+		if e.Switch && e.Velocity > 0 {
+			playing[key] = append(playing[key], len(notes))
+			notes = append(notes, timedNote{
+				start: time,
+				rdnd: synth.RawDelayedNoteData{
+					Note:       synth.NoteToString(int(e.Key) - midiNoteOffset),
+					Instrument: instrument,
+					Volume:     float32(e.Velocity) / 127,
+					Pan:        pans[e.Channel],
+				},
+			})
+		} else if len(playing[key]) > 0 {
+			i := playing[key][0]
+			playing[key] = playing[key][1:]
+
+			notes[i].rdnd.Duration = time - notes[i].start
 		}
 	}
 
-	return accum
+	// Notes that are never switched off last until the end of the track.
+	end, err := tm.seconds(tick)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, is := range playing {
+		for _, i := range is {
+			notes[i].rdnd.Duration = end - notes[i].start
+		}
+	}
+
+	return notes, nil
+}
+
+// Ordering a set of timed notes by their start time, and turning their start
+// times into delays from the note before.
+func arrangeNotes(notes []timedNote) []synth.RawDelayedNoteData {
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].start < notes[j].start
+	})
+
+	rdnds := make([]synth.RawDelayedNoteData, len(notes))
+	var last float32 = 0
+	for i, n := range notes {
+		rdnds[i] = n.rdnd
+		rdnds[i].Delay = n.start - last
+		last = n.start
+	}
+
+	return rdnds
 }
 
 // Dealing with synth.RawDelayedNoteData from a MIDI file.
@@ -147,28 +233,233 @@ func (a MIDIArrangement) ReadNoteArrangement(reader io.Reader) ([]synth.RawDelay
 			return []synth.RawDelayedNoteData{}, errors.New("Malformed track length.")
 		}
 
-		return constructNoteArrangement(m.Header, m.Tracks[0]), nil
+		notes, err := constructNotes(makeTempoMap(m.Header.Division, m.Tracks), m.Tracks[0])
+		if err != nil {
+			return []synth.RawDelayedNoteData{}, err
+		}
+
+		return arrangeNotes(notes), nil
 	case 1:
-		accum := []synth.RawDelayedNoteData{}
+		// Tracks are played simultaneously, sharing a single tempo map that's
+		// usually kept in the first track.
+		tm := makeTempoMap(m.Header.Division, m.Tracks)
+
+		notes := []timedNote{}
 		for i := 0; i < len(m.Tracks); i++ {
-			accum = mergeNoteArrangements(accum, constructNoteArrangement(m.Header, m.Tracks[i]))
+			trackNotes, err := constructNotes(tm, m.Tracks[i])
+			if err != nil {
+				return []synth.RawDelayedNoteData{}, err
+			}
+
+			notes = append(notes, trackNotes...)
 		}
 
-		return accum, nil
+		return arrangeNotes(notes), nil
 	case 2:
+		// Tracks are independent sequences, each with their own tempo map.
 		accum := []synth.RawDelayedNoteData{}
 		for i := 0; i < len(m.Tracks); i++ {
-			accum = append(accum, constructNoteArrangement(m.Header, m.Tracks[i])...)
+			notes, err := constructNotes(makeTempoMap(m.Header.Division, m.Tracks[i:i+1]), m.Tracks[i])
+			if err != nil {
+				return []synth.RawDelayedNoteData{}, err
+			}
+
+			accum = append(accum, arrangeNotes(notes)...)
 		}
 
 		return accum, nil
 	default:
 		return []synth.RawDelayedNoteData{}, errors.New("Unexpected format.")
 	}
+}
+
+// Converting a real time in seconds to an absolute tick in a written file.
+func secondsToTick(seconds float32) uint {
+	ticks := float64(seconds) * 1000000 / float64(writeTempo) * float64(writeDivision)
+	if ticks < 0 {
+		return 0
+	}
+
+	return uint(math.Floor(ticks + 0.5))
+}
+
+// Converting the volume of a note to a note on velocity.
+func volumeToVelocity(volume float32) uint8 {
+	if volume == 0 || volume >= 1 {
+		return 127
+	}
+
+	velocity := uint8(math.Floor(float64(volume)*127 + 0.5))
+	if velocity < 1 {
+		return 1
+	}
+
+	return velocity
+}
+
+// Converting a MIDI Pan controller value, where 64 is the centre, to a pan.
+func valueToPan(value uint8) float32 {
+	if value < 64 {
+		return (float32(value) - 64) / 64
+	}
+
+	return (float32(value) - 64) / 63
+}
+
+// Converting a pan to a MIDI Pan controller value.
+func panToValue(pan float32) uint8 {
+	if pan < 0 {
+		return uint8(math.Max(0, math.Floor(64+float64(pan)*64+0.5)))
+	}
+
+	return uint8(math.Min(127, math.Floor(64+float64(pan)*63+0.5)))
+}
+
+// Constructing a MIDI track from a set of notes that should all be played on a
+// single channel. The channel's pan is changed before any note that needs it,
+// so notes starting on the same tick share a single pan.
+func constructTrack(notes []synth.RawDelayedNoteData, starts []float32, channel uint8) (midi.Track, error) {
+	type timedEvent struct {
+		tick  uint
+		event midi.Event
+	}
+
+	// The note each key is sounding, as the index of its note off in timed.
+	sounding := map[uint8]int{}
+
+	timed := []timedEvent{}
+	var pan float32 = 0
+	for i, note := range notes {
+		key, err := synth.NoteToInt(note.Note)
+		if err != nil {
+			return nil, err
+		}
+
+		key += midiNoteOffset
+		if key < 0 || key > 127 {
+			return nil, errors.New("Note out of MIDI range: " + note.Note)
+		}
+
+		on := midi.Event{Kind: midi.NoteEvent, Switch: true, Channel: channel, Key: uint8(key), Velocity: volumeToVelocity(note.Volume)}
+		off := on
+		off.Switch = false
+		off.Velocity = 0
+
+		if note.Pan != pan {
+			pan = note.Pan
+			timed = append(timed, timedEvent{secondsToTick(starts[i]), midi.Event{
+				Kind:       midi.ControlEvent,
+				Channel:    channel,
+				Controller: panController,
+				Value:      panToValue(pan),
+			}})
+		}
+
+		// Notes too short to last a tick are given one, since a note off on
+		// the same tick would be sorted in front of its note on.
+		start := secondsToTick(starts[i])
+		end := secondsToTick(starts[i] + note.Duration)
+		if end <= start {
+			end = start + 1
+		}
+
+		// A key can only sound once on a channel, and readers pair note offs
+		// with note ons in order, so a note still sounding is ended where its
+		// key is struck again. Notes struck on the same tick are merged.
+		if j, ok := sounding[on.Key]; ok && timed[j-1].tick <= start && start < timed[j].tick {
+			if timed[j-1].tick == start {
+				if end > timed[j].tick {
+					timed[j].tick = end
+				}
+
+				continue
+			}
+
+			timed[j].tick = start
+		}
+
+		sounding[on.Key] = len(timed) + 1
+		timed = append(timed, timedEvent{start, on}, timedEvent{end, off})
+	}
+
+	// On the same tick note offs come first so that a repeated note isn't cut
+	// off by the end of the one before it, then control changes so that they
+	// apply to the note ons after them.
+	rank := func(e midi.Event) int {
+		switch {
+		case e.Kind == midi.NoteEvent && !e.Switch:
+			return 0
+		case e.Kind == midi.ControlEvent:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	sort.SliceStable(timed, func(i, j int) bool {
+		if timed[i].tick != timed[j].tick {
+			return timed[i].tick < timed[j].tick
+		}
+
+		return rank(timed[i].event) < rank(timed[j].event)
+	})
+
+	track := midi.Track{}
+	var last uint = 0
+	for _, t := range timed {
+		t.event.Delay = t.tick - last
+		last = t.tick
+
+		track = append(track, t.event)
+	}
 
-	return []synth.RawDelayedNoteData{}, nil
+	return track, nil
 }
 
+// Writing out a format 1 MIDI file: a first track holding the tempo, followed
+// by a track for every instrument used in the arrangement, named after it.
 func (a MIDIArrangement) WriteNoteArrangement(writer io.Writer, notes []synth.RawDelayedNoteData) error {
-	return errors.New("WriteNoteArrangement not yet implemented.")
+	instruments := []string{}
+	grouped := map[string][]synth.RawDelayedNoteData{}
+	starts := map[string][]float32{}
+
+	var time float32 = 0
+	for _, note := range notes {
+		time += note.Delay
+
+		if _, ok := grouped[note.Instrument]; !ok {
+			instruments = append(instruments, note.Instrument)
+		}
+
+		grouped[note.Instrument] = append(grouped[note.Instrument], note)
+		starts[note.Instrument] = append(starts[note.Instrument], time)
+	}
+
+	m := midi.MIDI{
+		Header: midi.Header{
+			Format:   1,
+			Division: writeDivision,
+		},
+		Tracks: []midi.Track{
+			midi.Track{midi.Event{Kind: midi.TempoEvent, Tempo: writeTempo}},
+		},
+	}
+
+	// Channel 10 is reserved for percussion, so it's skipped over.
+	channel := uint8(0)
+	for _, instrument := range instruments {
+		track, err := constructTrack(grouped[instrument], starts[instrument], channel)
+		if err != nil {
+			return err
+		}
+
+		m.Tracks = append(m.Tracks, append(midi.Track{midi.Event{Kind: midi.NameEvent, Name: instrument}}, track...))
+
+		channel = (channel + 1) % 16
+		if channel == 9 {
+			channel++
+		}
+	}
+
+	return m.Write(writer)
 }
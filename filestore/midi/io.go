@@ -114,7 +114,8 @@ func ReadHeader(reader io.Reader) (Header, error) {
 	}, nil
 }
 
-// Reading an event from in from a reader.
+// Reading an event from in from a reader. Events that should be skipped still
+// carry their delay, so that it can be added on to the next event.
 func ReadEvent(reader io.Reader) (Event, bool, error) {
 	delay, err := varInt(reader)
 	if err != nil {
@@ -141,7 +142,7 @@ func ReadEvent(reader io.Reader) (Event, bool, error) {
 			return Event{}, false, err
 		}
 
-		return Event{}, true, nil
+		return Event{Delay: delay}, true, nil
 	// Loading a meta event.
 	case 0xFF:
 		t, err := readByte(reader)
@@ -156,7 +157,9 @@ func ReadEvent(reader io.Reader) (Event, bool, error) {
 			if err != nil {
 				return Event{}, false, err
 			}
-		case 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x7F:
+
+			return Event{Delay: delay}, true, nil
+		case 0x03:
 			len, err := varInt(reader)
 			if err != nil {
 				return Event{}, false, err
@@ -167,49 +170,82 @@ func ReadEvent(reader io.Reader) (Event, bool, error) {
 				return Event{}, false, err
 			}
 
-			return Event{}, true, nil
+			return Event{
+				Delay: delay,
+				Kind:  NameEvent,
+				Name:  string(bs),
+			}, false, nil
+		case 0x01, 0x02, 0x04, 0x05, 0x06, 0x07, 0x7F:
+			len, err := varInt(reader)
+			if err != nil {
+				return Event{}, false, err
+			}
+
+			bs := make([]byte, len)
+			if _, err := reader.Read(bs); err != nil {
+				return Event{}, false, err
+			}
+
+			return Event{Delay: delay}, true, nil
 		case 0x20:
 			bs := make([]byte, 2)
 			if _, err := reader.Read(bs); err != nil {
 				return Event{}, false, err
 			}
 
-			return Event{}, true, nil
+			return Event{Delay: delay}, true, nil
 		case 0x2F:
 			bs := make([]byte, 1)
 			if _, err := reader.Read(bs); err != nil {
 				return Event{}, false, err
 			}
 
-			return Event{}, true, nil
+			return Event{Delay: delay}, true, nil
 		case 0x51:
 			bs := make([]byte, 4)
 			if _, err := reader.Read(bs); err != nil {
 				return Event{}, false, err
 			}
 
-			return Event{}, true, nil
+			return Event{
+				Delay: delay,
+				Kind:  TempoEvent,
+				Tempo: uint32(bs[1])<<16 | uint32(bs[2])<<8 | uint32(bs[3]),
+			}, false, nil
 		case 0x54:
 			bs := make([]byte, 6)
 			if _, err := reader.Read(bs); err != nil {
 				return Event{}, false, err
 			}
 
-			return Event{}, true, nil
+			return Event{Delay: delay}, true, nil
 		case 0x58:
 			bs := make([]byte, 5)
 			if _, err := reader.Read(bs); err != nil {
 				return Event{}, false, err
 			}
 
-			return Event{}, true, nil
+			return Event{Delay: delay}, true, nil
 		case 0x59:
 			bs := make([]byte, 3)
 			if _, err := reader.Read(bs); err != nil {
 				return Event{}, false, err
 			}
 
-			return Event{}, true, nil
+			return Event{Delay: delay}, true, nil
+		default:
+			// Any other meta event is skipped over using its length.
+			len, err := varInt(reader)
+			if err != nil {
+				return Event{}, false, err
+			}
+
+			bs := make([]byte, len)
+			if _, err := reader.Read(bs); err != nil {
+				return Event{}, false, err
+			}
+
+			return Event{Delay: delay}, true, nil
 		}
 	default:
 		kind := b >> 4
@@ -234,26 +270,40 @@ func ReadEvent(reader io.Reader) (Event, bool, error) {
 			}
 
 			return Event{
-				delay,
-				s,
-				uint8(b & 0x0F),
-				uint8(key),
-				uint8(velocity),
+				Delay:    delay,
+				Kind:     NoteEvent,
+				Switch:   s,
+				Channel:  uint8(b & 0x0F),
+				Key:      uint8(key),
+				Velocity: uint8(velocity),
 			}, false, nil
-		case 0xA, 0xB, 0xE:
+		case 0xB:
 			bs := make([]byte, 2)
 			if _, err := reader.Read(bs); err != nil {
 				return Event{}, false, err
 			}
 
-			return Event{}, true, nil
+			return Event{
+				Delay:      delay,
+				Kind:       ControlEvent,
+				Channel:    uint8(b & 0x0F),
+				Controller: bs[0],
+				Value:      bs[1],
+			}, false, nil
+		case 0xA, 0xE:
+			bs := make([]byte, 2)
+			if _, err := reader.Read(bs); err != nil {
+				return Event{}, false, err
+			}
+
+			return Event{Delay: delay}, true, nil
 		case 0xC, 0xD:
 			bs := make([]byte, 1)
 			if _, err := reader.Read(bs); err != nil {
 				return Event{}, false, err
 			}
 
-			return Event{}, true, nil
+			return Event{Delay: delay}, true, nil
 		default:
 			return Event{}, false, errors.New(fmt.Sprintf("Unrecognized note kind: 0x%X", kind))
 		}
@@ -277,6 +327,7 @@ func ReadTrack(reader io.Reader) (Track, error) {
 
 	track := Track{}
 	buf := bytes.NewBuffer(chunk.Bytes)
+	var carry uint = 0
 	for buf.Len() > 0 {
 		event, skip, err := ReadEvent(buf)
 		if err != nil {
@@ -284,9 +335,13 @@ func ReadTrack(reader io.Reader) (Track, error) {
 		}
 
 		if skip {
+			carry += event.Delay
 			continue
 		}
 
+		event.Delay += carry
+		carry = 0
+
 		track = append(track, event)
 	}
 
@@ -327,9 +382,109 @@ func ReadFile(path string) (*MIDI, error) {
 	return Read(file)
 }
 
+// Writing a variable quantity int per the MIDI specification.
+func writeVarInt(writer io.Writer, n uint) error {
+	bs := []byte{byte(n & 0x7F)}
+	for n >>= 7; n > 0; n >>= 7 {
+		bs = append([]byte{byte(n&0x7F) | 0x80}, bs...)
+	}
+
+	_, err := writer.Write(bs)
+	return err
+}
+
+// Writing a chunk out to a writer.
+func WriteChunk(writer io.Writer, chunk Chunk) error {
+	if len(chunk.Title) != 4 {
+		return errors.New("Invalid chunk title.")
+	}
+
+	if _, err := writer.Write([]byte(chunk.Title)); err != nil {
+		return err
+	}
+
+	if err := binary.Write(writer, binary.BigEndian, uint32(len(chunk.Bytes))); err != nil {
+		return err
+	}
+
+	_, err := writer.Write(chunk.Bytes)
+	return err
+}
+
+// Writing the MIDI header out to a writer.
+func WriteHeader(writer io.Writer, header Header) error {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.BigEndian, header.Format)
+	binary.Write(buf, binary.BigEndian, header.Tracks)
+	binary.Write(buf, binary.BigEndian, header.Division)
+
+	return WriteChunk(writer, Chunk{"MThd", 6, buf.Bytes()})
+}
+
+// Writing a single event out to a writer.
+func WriteEvent(writer io.Writer, event Event) error {
+	if err := writeVarInt(writer, event.Delay); err != nil {
+		return err
+	}
+
+	var bs []byte
+	switch event.Kind {
+	case NoteEvent:
+		status := byte(0x80)
+		if event.Switch {
+			status = 0x90
+		}
+
+		bs = []byte{status | (event.Channel & 0x0F), event.Key & 0x7F, event.Velocity & 0x7F}
+	case TempoEvent:
+		bs = []byte{0xFF, 0x51, 0x03, byte(event.Tempo >> 16), byte(event.Tempo >> 8), byte(event.Tempo)}
+	case ControlEvent:
+		bs = []byte{0xB0 | (event.Channel & 0x0F), event.Controller & 0x7F, event.Value & 0x7F}
+	case NameEvent:
+		buf := bytes.NewBuffer([]byte{0xFF, 0x03})
+		if err := writeVarInt(buf, uint(len(event.Name))); err != nil {
+			return err
+		}
+
+		buf.WriteString(event.Name)
+		bs = buf.Bytes()
+	default:
+		return errors.New(fmt.Sprintf("Unrecognized event kind: %d", event.Kind))
+	}
+
+	_, err := writer.Write(bs)
+	return err
+}
+
+// Writing a track out to a writer, finishing it with an end of track event.
+func WriteTrack(writer io.Writer, track Track) error {
+	buf := new(bytes.Buffer)
+	for _, event := range track {
+		if err := WriteEvent(buf, event); err != nil {
+			return err
+		}
+	}
+
+	buf.Write([]byte{0x00, 0xFF, 0x2F, 0x00})
+
+	return WriteChunk(writer, Chunk{"MTrk", uint32(buf.Len()), buf.Bytes()})
+}
+
 // Writing a MIDI structure out to some io.Writer.
 func (m *MIDI) Write(writer io.Writer) error {
-	fmt.Println("Writing MIDI files is not yet supported.")
+	header := m.Header
+	header.Tracks = uint16(len(m.Tracks))
+
+	if err := WriteHeader(writer, header); err != nil {
+		return err
+	}
+
+	for _, track := range m.Tracks {
+		if err := WriteTrack(writer, track); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -342,5 +497,5 @@ func (m *MIDI) WriteFile(path string) error {
 	}
 	defer file.Close()
 
-	return m.WriteFile(path)
+	return m.Write(file)
 }
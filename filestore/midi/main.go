@@ -17,13 +17,31 @@ type Header struct {
 // A set of MIDI events that constitute a track.
 type Track []Event
 
+// The kind of a single MIDI event.
+type EventKind uint8
+
+const (
+	NoteEvent    EventKind = iota // A note on or note off.
+	TempoEvent                    // A Set Tempo meta event.
+	ControlEvent                  // A control change.
+	NameEvent                     // A Sequence/Track Name meta event.
+)
+
 // A single MIDI event.
 type Event struct {
 	Delay    uint
+	Kind     EventKind
 	Switch   bool
 	Channel  uint8
 	Key      uint8
 	Velocity uint8
+
+	Tempo uint32 // Microseconds per quarter note for a TempoEvent.
+
+	Controller uint8 // The controller number for a ControlEvent.
+	Value      uint8 // The controller value for a ControlEvent.
+
+	Name string // The name of the track for a NameEvent.
 }
 
 // The entire structure of a MIDI file.
@@ -0,0 +1,426 @@
+package filestore
+
+import (
+	"bytes"
+	"github.com/crockeo/go-tuner/filestore/midi"
+	"github.com/crockeo/go-tuner/synth"
+	"math"
+	"testing"
+)
+
+// Checking that a time in seconds is within a microsecond of another.
+func closeSeconds(got, want float32) bool {
+	return math.Abs(float64(got-want)) < 1e-6
+}
+
+func TestTempoMap(t *testing.T) {
+	// 480 ticks per quarter note, starting at the default 120 BPM and changing
+	// to 60 BPM on the second beat, then to 240 BPM on the fourth. The tempo
+	// track and the notes are kept apart, like in a format 1 file.
+	tm := makeTempoMap(480, []midi.Track{
+		{
+			{Delay: 480, Kind: midi.TempoEvent, Tempo: 1000000},
+			{Delay: 960, Kind: midi.TempoEvent, Tempo: 250000},
+		},
+		{
+			{Delay: 0, Kind: midi.NoteEvent, Switch: true, Key: 60, Velocity: 100},
+			{Delay: 1920, Kind: midi.NoteEvent, Key: 60},
+		},
+	})
+
+	tests := []struct {
+		tick    uint
+		seconds float32
+	}{
+		{0, 0},
+		{240, 0.25},
+		{480, 0.5},
+		{720, 1},
+		{960, 1.5},
+		{1440, 2.5},
+		{1680, 2.625},
+		{1920, 2.75},
+	}
+
+	for _, test := range tests {
+		seconds, err := tm.seconds(test.tick)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !closeSeconds(seconds, test.seconds) {
+			t.Errorf("Tick %d: expected %vs, got %vs.", test.tick, test.seconds, seconds)
+		}
+	}
+}
+
+func TestTempoMapTracks(t *testing.T) {
+	// Tempo changes from separate tracks are merged in tick order.
+	tm := makeTempoMap(96, []midi.Track{
+		{{Delay: 192, Kind: midi.TempoEvent, Tempo: 1000000}},
+		{{Delay: 96, Kind: midi.TempoEvent, Tempo: 250000}},
+	})
+
+	seconds, err := tm.seconds(288)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := float32(0.5 + 0.25 + 1); !closeSeconds(seconds, want) {
+		t.Errorf("Expected %vs, got %vs.", want, seconds)
+	}
+}
+
+func TestConvertTick(t *testing.T) {
+	tests := []struct {
+		division int16
+		tempo    uint32
+		delay    uint
+		seconds  float32
+	}{
+		{96, 500000, 96, 0.5},
+		{480, 1000000, 240, 0.5},
+		{480, 250000, 1920, 1},
+	}
+
+	for _, test := range tests {
+		seconds, err := convertTick(test.division, test.tempo, test.delay)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !closeSeconds(seconds, test.seconds) {
+			t.Errorf("Division %d, tempo %d, %d ticks: expected %vs, got %vs.", test.division, test.tempo, test.delay, test.seconds, seconds)
+		}
+	}
+
+	if _, err := convertTick(0, defaultTempo, 1); err == nil {
+		t.Error("Expected a division of 0 ticks per quarter note to be rejected.")
+	}
+}
+
+// Making an SMPTE division from a frame rate and a number of ticks per frame.
+func smpteDivision(fps int8, ticksPerFrame uint8) int16 {
+	return int16(uint16(uint8(-fps))<<8 | uint16(ticksPerFrame))
+}
+
+func TestConvertTickSMPTE(t *testing.T) {
+	tests := []struct {
+		fps           int8
+		ticksPerFrame uint8
+		delay         uint
+		seconds       float32
+	}{
+		{24, 4, 96, 1},
+		{25, 40, 500, 0.5},
+		{29, 4, 120, 1.001},
+		{30, 80, 4800, 2},
+	}
+
+	for _, test := range tests {
+		// The tempo is ignored, so changing it mustn't change the time.
+		for _, tempo := range []uint32{defaultTempo, 1000000} {
+			seconds, err := convertTick(smpteDivision(test.fps, test.ticksPerFrame), tempo, test.delay)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !closeSeconds(seconds, test.seconds) {
+				t.Errorf("%d fps, %d ticks per frame, %d ticks: expected %vs, got %vs.", test.fps, test.ticksPerFrame, test.delay, test.seconds, seconds)
+			}
+		}
+	}
+
+	// SMPTE timing runs through the tempo map too.
+	tm := makeTempoMap(smpteDivision(25, 40), []midi.Track{{{Delay: 500, Kind: midi.TempoEvent, Tempo: 1000000}}})
+	if seconds, err := tm.seconds(1500); err != nil || !closeSeconds(seconds, 1.5) {
+		t.Errorf("Expected a tempo change not to move SMPTE ticks, got %vs (%v).", seconds, err)
+	}
+}
+
+func TestConvertTickBadSMPTE(t *testing.T) {
+	tests := []struct {
+		fps           int8
+		ticksPerFrame uint8
+	}{
+		{23, 4},
+		{60, 4},
+		{25, 0},
+	}
+
+	for _, test := range tests {
+		if _, err := convertTick(smpteDivision(test.fps, test.ticksPerFrame), defaultTempo, 1); err == nil {
+			t.Errorf("Expected %d fps with %d ticks per frame to be rejected.", test.fps, test.ticksPerFrame)
+		}
+	}
+}
+
+func TestMIDIRoundTrip(t *testing.T) {
+	tick := float32(writeTempo) / 1000000 / float32(writeDivision)
+
+	tests := []struct {
+		name  string
+		notes []synth.RawDelayedNoteData
+		want  []synth.RawDelayedNoteData
+	}{
+		{
+			"Separate notes",
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "C4", Duration: 0.5, Instrument: "guitar"},
+				{Delay: 0.5, Note: "A4", Duration: 0.25, Instrument: "guitar", Volume: 0.5},
+				{Delay: 0, Note: "C-1", Duration: 0.25, Instrument: "guitar"},
+			},
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "C4", Duration: 0.5, Instrument: "guitar", Volume: 1},
+				{Delay: 0.5, Note: "A4", Duration: 0.25, Instrument: "guitar", Volume: 64.0 / 127},
+				{Delay: 0, Note: "C-1", Duration: 0.25, Instrument: "guitar", Volume: 1},
+			},
+		},
+		{
+			// Notes too short for a tick last one, and end after they start.
+			"Short notes",
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "C4", Duration: 0, Instrument: "guitar"},
+				{Delay: 0.5, Note: "C4", Duration: 0.0001, Instrument: "guitar"},
+			},
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "C4", Duration: tick, Instrument: "guitar", Volume: 1},
+				{Delay: 0.5, Note: "C4", Duration: tick, Instrument: "guitar", Volume: 1},
+			},
+		},
+		{
+			// A key struck again while it's sounding ends the note before.
+			"Overlapping notes",
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "A4", Duration: 1, Instrument: "guitar"},
+				{Delay: 0.25, Note: "A4", Duration: 0.25, Instrument: "guitar"},
+				{Delay: 0, Note: "E4", Duration: 1, Instrument: "guitar"},
+			},
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "A4", Duration: 0.25, Instrument: "guitar", Volume: 1},
+				{Delay: 0.25, Note: "A4", Duration: 0.25, Instrument: "guitar", Volume: 1},
+				{Delay: 0, Note: "E4", Duration: 1, Instrument: "guitar", Volume: 1},
+			},
+		},
+		{
+			// Every instrument is written to a track named after it.
+			"Instruments",
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "C4", Duration: 1, Instrument: "guitar"},
+				{Delay: 0.25, Note: "C4", Duration: 1, Instrument: "bell"},
+				{Delay: 0.25, Note: "E4", Duration: 0.5, Instrument: "guitar", Pan: -1},
+			},
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "C4", Duration: 1, Instrument: "guitar", Volume: 1},
+				{Delay: 0.25, Note: "C4", Duration: 1, Instrument: "bell", Volume: 1},
+				{Delay: 0.25, Note: "E4", Duration: 0.5, Instrument: "guitar", Volume: 1, Pan: -1},
+			},
+		},
+		{
+			// A key struck twice at once sounds once, for the longer note.
+			"Doubled notes",
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "A4", Duration: 0.5, Instrument: "guitar"},
+				{Delay: 0, Note: "A4", Duration: 1, Instrument: "guitar"},
+			},
+			[]synth.RawDelayedNoteData{
+				{Delay: 0, Note: "A4", Duration: 1, Instrument: "guitar", Volume: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		var buffer bytes.Buffer
+		if err := (MIDIArrangement{}).WriteNoteArrangement(&buffer, test.notes); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		notes, err := MIDIArrangement{}.ReadNoteArrangement(&buffer)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		checkNotes(t, test.name, test.want, notes)
+	}
+}
+
+func TestMIDIKeys(t *testing.T) {
+	var buffer bytes.Buffer
+	notes := []synth.RawDelayedNoteData{
+		{Note: "C-1", Duration: 1, Instrument: "guitar"},
+		{Note: "C4", Duration: 1, Instrument: "guitar"},
+		{Note: "A4", Duration: 1, Instrument: "guitar"},
+		{Note: "G9", Duration: 1, Instrument: "guitar"},
+	}
+
+	if err := (MIDIArrangement{}).WriteNoteArrangement(&buffer, notes); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := midi.Read(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []uint8{}
+	for _, e := range m.Tracks[len(m.Tracks)-1] {
+		if e.Kind == midi.NoteEvent && e.Switch {
+			keys = append(keys, e.Key)
+		}
+	}
+
+	want := []uint8{0, 60, 69, 127}
+	if len(keys) != len(want) {
+		t.Fatalf("Expected keys %v, got %v.", want, keys)
+	}
+
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Fatalf("Expected keys %v, got %v.", want, keys)
+		}
+	}
+
+	for _, note := range []string{"B-2", "G#9"} {
+		err := MIDIArrangement{}.WriteNoteArrangement(&bytes.Buffer{}, []synth.RawDelayedNoteData{{Note: note, Duration: 1, Instrument: "guitar"}})
+		if err == nil {
+			t.Errorf("Expected %s to be out of MIDI's range.", note)
+		}
+	}
+}
+
+// Checking that a set of notes read from a file match the ones expected, to
+// within a microsecond.
+func checkNotes(t *testing.T, name string, want, got []synth.RawDelayedNoteData) {
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d notes, got %d: %v.", name, len(want), len(got), got)
+	}
+
+	for i := range want {
+		w, g := want[i], got[i]
+		if g.Note != w.Note || g.Instrument != w.Instrument || g.Pan != w.Pan || !closeSeconds(g.Delay, w.Delay) ||
+			!closeSeconds(g.Duration, w.Duration) || math.Abs(float64(g.Volume-w.Volume)) > 1e-6 {
+			t.Errorf("%s: note %d: expected %+v, got %+v.", name, i, w, g)
+		}
+	}
+}
+
+func TestConstructNotes(t *testing.T) {
+	on := func(delay uint, channel, key uint8) midi.Event {
+		return midi.Event{Delay: delay, Kind: midi.NoteEvent, Switch: true, Channel: channel, Key: key, Velocity: 127}
+	}
+	off := func(delay uint, channel, key uint8) midi.Event {
+		return midi.Event{Delay: delay, Kind: midi.NoteEvent, Channel: channel, Key: key}
+	}
+
+	// At 480 ticks per quarter note and 120 BPM, 480 ticks are half a second.
+	tests := []struct {
+		name  string
+		track midi.Track
+		want  []timedNote
+	}{
+		{
+			"Note on and off",
+			midi.Track{on(0, 0, 60), off(480, 0, 60)},
+			[]timedNote{{0, synth.RawDelayedNoteData{Note: "C4", Duration: 0.5}}},
+		},
+		{
+			"Keys",
+			midi.Track{on(0, 0, 0), on(0, 0, 69), on(0, 0, 127), off(240, 0, 0), off(0, 0, 69), off(0, 0, 127)},
+			[]timedNote{
+				{0, synth.RawDelayedNoteData{Note: "C-1", Duration: 0.25}},
+				{0, synth.RawDelayedNoteData{Note: "A4", Duration: 0.25}},
+				{0, synth.RawDelayedNoteData{Note: "G9", Duration: 0.25}},
+			},
+		},
+		{
+			"Velocity",
+			midi.Track{{Kind: midi.NoteEvent, Switch: true, Key: 60, Velocity: 64}, off(480, 0, 60)},
+			[]timedNote{{0, synth.RawDelayedNoteData{Note: "C4", Duration: 0.5, Volume: 64.0 / 127}}},
+		},
+		{
+			"Note on without velocity",
+			midi.Track{on(0, 0, 60), {Delay: 240, Kind: midi.NoteEvent, Switch: true, Key: 60}},
+			[]timedNote{{0, synth.RawDelayedNoteData{Note: "C4", Duration: 0.25}}},
+		},
+		{
+			// Overlapping notes on one key are ended in the order they started.
+			"Overlapping notes",
+			midi.Track{on(0, 0, 69), on(240, 0, 69), off(240, 0, 69), off(480, 0, 69)},
+			[]timedNote{
+				{0, synth.RawDelayedNoteData{Note: "A4", Duration: 0.5}},
+				{0.25, synth.RawDelayedNoteData{Note: "A4", Duration: 0.75}},
+			},
+		},
+		{
+			"Channels",
+			midi.Track{on(0, 0, 69), on(240, 1, 69), off(240, 1, 69), off(480, 0, 69)},
+			[]timedNote{
+				{0, synth.RawDelayedNoteData{Note: "A4", Duration: 1}},
+				{0.25, synth.RawDelayedNoteData{Note: "A4", Duration: 0.25}},
+			},
+		},
+		{
+			"Note off without a note on",
+			midi.Track{off(0, 0, 60), on(480, 0, 60), off(480, 0, 60), off(480, 0, 60)},
+			[]timedNote{{0.5, synth.RawDelayedNoteData{Note: "C4", Duration: 0.5}}},
+		},
+		{
+			// Notes never switched off last until the end of the track.
+			"Unterminated notes",
+			midi.Track{on(0, 0, 60), on(480, 0, 64), {Delay: 960, Kind: midi.ControlEvent, Controller: 7, Value: 100}},
+			[]timedNote{
+				{0, synth.RawDelayedNoteData{Note: "C4", Duration: 1.5}},
+				{0.5, synth.RawDelayedNoteData{Note: "E4", Duration: 1}},
+			},
+		},
+		{
+			"Pan",
+			midi.Track{{Kind: midi.ControlEvent, Channel: 1, Controller: panController, Value: 0}, on(0, 1, 60), on(0, 0, 64), off(480, 1, 60), off(0, 0, 64)},
+			[]timedNote{
+				{0, synth.RawDelayedNoteData{Note: "C4", Duration: 0.5, Pan: -1}},
+				{0, synth.RawDelayedNoteData{Note: "E4", Duration: 0.5}},
+			},
+		},
+		{
+			"Instruments",
+			midi.Track{
+				on(0, 0, 60), off(480, 0, 60),
+				{Kind: midi.NameEvent, Name: "bell"}, on(0, 0, 60), off(480, 0, 60),
+				{Kind: midi.NameEvent, Name: "Acoustic Grand Piano"}, on(0, 0, 60), off(480, 0, 60),
+			},
+			[]timedNote{
+				{0, synth.RawDelayedNoteData{Note: "C4", Duration: 0.5}},
+				{0.5, synth.RawDelayedNoteData{Note: "C4", Duration: 0.5, Instrument: "bell"}},
+				{1, synth.RawDelayedNoteData{Note: "C4", Duration: 0.5}},
+			},
+		},
+	}
+
+	tm := makeTempoMap(480, nil)
+	for _, test := range tests {
+		notes, err := constructNotes(tm, test.track)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		// Filling in what most notes share.
+		want := make([]synth.RawDelayedNoteData, len(test.want))
+		got := make([]synth.RawDelayedNoteData, len(notes))
+		for i, n := range test.want {
+			want[i] = n.rdnd
+			want[i].Delay = n.start
+			if want[i].Instrument == "" {
+				want[i].Instrument = "guitar"
+			}
+			if want[i].Volume == 0 {
+				want[i].Volume = 1
+			}
+		}
+		for i, n := range notes {
+			got[i] = n.rdnd
+			got[i].Delay = n.start
+		}
+
+		checkNotes(t, test.name, want, got)
+	}
+}
@@ -0,0 +1,698 @@
+package filestore
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/crockeo/go-tuner/filestore/musicxml"
+	"github.com/crockeo/go-tuner/synth"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultQuarterTempo float64 = 120 // The quarter notes per minute before any tempo marking.
+	forteVelocity       float64 = 90  // The MIDI velocity a MusicXML dynamics of 100 stands for.
+	xmlDivisions        int     = 480 // The divisions per quarter note of written files.
+	xmlQuarterTempo     float64 = 120 // The quarter notes per minute of written files.
+	xmlMeasureQuarters  int     = 4   // The quarter notes per measure of written files, which are in 4/4.
+)
+
+var (
+	// The semitones above C of each step.
+	xmlSteps = map[string]int{"C": 0, "D": 2, "E": 4, "F": 5, "G": 7, "A": 9, "B": 11}
+
+	// Every note in an octave, spelled with sharps as a step and an alter.
+	xmlSpellings = [12]struct {
+		step  string
+		alter int
+	}{
+		{"C", 0}, {"C", 1}, {"D", 0}, {"D", 1}, {"E", 0}, {"F", 0},
+		{"F", 1}, {"G", 0}, {"G", 1}, {"A", 0}, {"A", 1}, {"B", 0},
+	}
+
+	// The quarter notes taken up by each written note value.
+	xmlNoteValues = []struct {
+		name     string
+		quarters float64
+	}{
+		{"whole", 4}, {"half", 2}, {"quarter", 1}, {"eighth", 0.5},
+		{"16th", 0.25}, {"32nd", 0.125}, {"64th", 0.0625}, {"128th", 0.03125},
+	}
+)
+
+// A change of tempo at a point in a score, measured in quarter notes.
+type xmlTempoChange struct {
+	quarter float64
+	tempo   float64 // Quarter notes per minute.
+}
+
+// Converting a point in a score, measured in quarter notes, to a real time
+// through a set of tempo changes sorted by when they happen.
+func xmlSeconds(tempos []xmlTempoChange, quarter float64) float32 {
+	var seconds float64 = 0
+	last, tempo := 0.0, defaultQuarterTempo
+
+	for _, c := range tempos {
+		if c.quarter >= quarter {
+			break
+		}
+
+		seconds += (c.quarter - last) * 60 / tempo
+		last, tempo = c.quarter, c.tempo
+	}
+
+	return float32(seconds + (quarter-last)*60/tempo)
+}
+
+// Finding the quarter notes per minute given by a metronome mark, if it gives
+// one.
+func metronomeTempo(m musicxml.Metronome) (float64, bool) {
+	perMinute, err := strconv.ParseFloat(strings.TrimSpace(m.PerMinute), 64)
+	if err != nil || perMinute <= 0 {
+		return 0, false
+	}
+
+	for _, v := range xmlNoteValues {
+		if v.name != m.BeatUnit {
+			continue
+		}
+
+		// Every dot adds half as much again as the one before it.
+		quarters, dot := v.quarters, v.quarters/2
+		for range m.BeatUnitDots {
+			quarters += dot
+			dot /= 2
+		}
+
+		return perMinute * quarters, true
+	}
+
+	return 0, false
+}
+
+// Finding the quarter notes per minute a <direction> or <sound> sets, if it
+// sets any. The played back tempo of a <sound> is preferred over what a
+// metronome mark shows.
+func elementTempo(e musicxml.Element) (float64, bool) {
+	switch e.XMLName.Local {
+	case "sound":
+		return e.Tempo, e.Tempo > 0
+	case "direction":
+		if e.Sound != nil && e.Sound.Tempo > 0 {
+			return e.Sound.Tempo, true
+		}
+
+		for _, dt := range e.DirectionTypes {
+			if dt.Metronome != nil {
+				if tempo, ok := metronomeTempo(*dt.Metronome); ok {
+					return tempo, true
+				}
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// Converting a MusicXML pitch to a note, keeping its spelling. Alters that
+// aren't a whole number of semitones are kept as a cent offset.
+func xmlPitchToNote(p musicxml.Pitch) (string, error) {
+	if _, ok := xmlSteps[p.Step]; !ok {
+		return "", errors.New("Invalid MusicXML step: " + p.Step)
+	}
+
+	semitones := math.Floor(p.Alter + 0.5)
+	cents := float32((p.Alter - semitones) * 100)
+
+	var accidental string
+	switch semitones {
+	case -2:
+		accidental = "bb"
+	case -1:
+		accidental = "b"
+	case 0:
+		accidental = ""
+	case 1:
+		accidental = "#"
+	case 2:
+		accidental = "##"
+	default:
+		return synth.Pitch{Note: p.Octave*12 + xmlSteps[p.Step] + int(semitones), Cents: cents}.String(), nil
+	}
+
+	note := fmt.Sprintf("%s%s%d", p.Step, accidental, p.Octave)
+	if cents != 0 {
+		note += fmt.Sprintf("%+gc", cents)
+	}
+
+	return note, nil
+}
+
+// Converting a note to a MusicXML pitch, keeping its spelling where it has one.
+func noteToXMLPitch(note string) (musicxml.Pitch, error) {
+	pitch, err := synth.ParsePitch(note)
+	if err != nil {
+		return musicxml.Pitch{}, err
+	}
+
+	// Frequencies are spelled as the closest note, with the difference in
+	// cents.
+	if pitch.Frequency > 0 {
+		n := pitch.Nearest()
+		cents := 1200 * math.Log2(float64(pitch.Frequency/synth.StandardTuning.Frequency(n)))
+		octave, i := n/12, n%12
+		if i < 0 {
+			octave, i = octave-1, i+12
+		}
+
+		return musicxml.Pitch{
+			Step:   xmlSpellings[i].step,
+			Alter:  float64(xmlSpellings[i].alter) + cents/100,
+			Octave: octave,
+		}, nil
+	}
+
+	// Otherwise the step is the letter the note was written with, and the
+	// octave the one that leaves the alter closest to nothing.
+	step := strings.ToUpper(note[:1])
+	offset := pitch.Note - xmlSteps[step]
+	octave := int(math.Floor(float64(offset)/12 + 0.5))
+
+	return musicxml.Pitch{
+		Step:   step,
+		Alter:  float64(offset-octave*12) + float64(pitch.Cents)/100,
+		Octave: octave,
+	}, nil
+}
+
+// Finding the registered instrument that best matches a part: one named
+// exactly like it, or else the first one named anywhere in it, like "piano" in
+// "Grand Piano". Parts matching nothing are played on the guitar.
+func partInstrument(names []string) string {
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := synth.GetInstrument(name); ok {
+			return name
+		}
+	}
+
+	for _, name := range names {
+		name = strings.ToLower(name)
+
+		best, at := "", len(name)
+		for _, instrument := range synth.InstrumentNames() {
+			if i := strings.Index(name, instrument); i >= 0 && i < at {
+				best, at = instrument, i
+			}
+		}
+
+		if best != "" {
+			return best
+		}
+	}
+
+	return "guitar"
+}
+
+// A note in a part, measured in quarter notes from the start of the score.
+type xmlNote struct {
+	start, end float64
+	rdnd       synth.RawDelayedNoteData
+}
+
+// Reading the notes and tempo changes in a single part. Measures follow on
+// from the furthest point reached in the measure before, so pickups and
+// measures of any length are kept as written.
+func readPart(part musicxml.Part, instrument string) ([]xmlNote, []xmlTempoChange, error) {
+	notes := []xmlNote{}
+	tempos := []xmlTempoChange{}
+
+	// The notes waiting on a tie, by pitch and then by voice.
+	tied := map[string]map[string]int{}
+
+	divisions := 1.0
+	measureStart := 0.0
+	for _, measure := range part.Measures {
+		cursor, furthest, chordStart := measureStart, measureStart, measureStart
+
+		for _, e := range measure.Elements {
+			switch e.XMLName.Local {
+			case "attributes":
+				if e.Divisions > 0 {
+					divisions = float64(e.Divisions)
+				}
+			case "backup":
+				cursor -= float64(e.Duration) / divisions
+			case "forward":
+				cursor += float64(e.Duration) / divisions
+			case "direction", "sound":
+				if tempo, ok := elementTempo(e); ok {
+					tempos = append(tempos, xmlTempoChange{cursor, tempo})
+				}
+			case "note":
+				if e.Grace != nil {
+					continue
+				}
+
+				length := float64(e.Duration) / divisions
+				if e.Chord == nil {
+					chordStart = cursor
+					cursor += length
+				}
+				start := chordStart
+
+				// A dynamics of 0 is silent, so the note is read like a rest.
+				if e.Pitch == nil || e.Rest != nil || e.Cue != nil || (e.Dynamics != nil && *e.Dynamics <= 0) {
+					break
+				}
+
+				name, err := xmlPitchToNote(*e.Pitch)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				ties := append([]musicxml.Tie{}, e.Ties...)
+				for _, n := range e.Notations {
+					ties = append(ties, n.Tied...)
+				}
+
+				startsTie, stopsTie := false, false
+				for _, tie := range ties {
+					startsTie = startsTie || tie.Type == "start"
+					stopsTie = stopsTie || tie.Type == "stop"
+				}
+
+				// A tied note carries on the note it's tied to, which is looked
+				// for in the same voice first.
+				i, found := -1, false
+				if stopsTie {
+					if i, found = tied[name][e.Voice]; !found {
+						for _, j := range tied[name] {
+							i, found = j, true
+							break
+						}
+					}
+				}
+
+				if found {
+					notes[i].end = math.Max(notes[i].end, start+length)
+					for voice, j := range tied[name] {
+						if j == i {
+							delete(tied[name], voice)
+						}
+					}
+				} else {
+					// Dynamics are a percentage of forte's velocity, which is
+					// scaled the same way MIDI velocities are. Notes without
+					// one are played at full volume.
+					var volume float32
+					if e.Dynamics != nil {
+						volume = float32(math.Max(0, math.Min(1, *e.Dynamics/100*forteVelocity/127)))
+					}
+
+					i = len(notes)
+					notes = append(notes, xmlNote{
+						start: start,
+						end:   start + length,
+						rdnd: synth.RawDelayedNoteData{
+							Note:       name,
+							Instrument: instrument,
+							Volume:     volume,
+						},
+					})
+				}
+
+				if startsTie {
+					if tied[name] == nil {
+						tied[name] = map[string]int{}
+					}
+					tied[name][e.Voice] = i
+				}
+			}
+
+			furthest = math.Max(furthest, cursor)
+		}
+
+		measureStart = furthest
+	}
+
+	return notes, tempos, nil
+}
+
+// Dealing with synth.RawDelayedNoteData from a MusicXML file. Parts are played
+// together, on the instrument that best matches their name. Repeats are not
+// played out, and only partwise scores can be read.
+type MusicXMLArrangement struct{}
+
+func (a MusicXMLArrangement) ReadNoteArrangement(reader io.Reader) ([]synth.RawDelayedNoteData, error) {
+	score, err := musicxml.Read(reader)
+	if err != nil {
+		return []synth.RawDelayedNoteData{}, err
+	}
+
+	names := map[string][]string{}
+	for _, sp := range score.PartList {
+		names[sp.ID] = append(names[sp.ID], sp.Name)
+		for _, si := range sp.Instruments {
+			names[sp.ID] = append(names[sp.ID], si.Name)
+		}
+	}
+
+	// Tempo markings are usually only written in the first part, but apply to
+	// every part, so every part is read before any times are worked out.
+	parts := [][]xmlNote{}
+	tempos := []xmlTempoChange{}
+	for _, part := range score.Parts {
+		notes, partTempos, err := readPart(part, partInstrument(names[part.ID]))
+		if err != nil {
+			return []synth.RawDelayedNoteData{}, err
+		}
+
+		parts = append(parts, notes)
+		tempos = append(tempos, partTempos...)
+	}
+
+	sort.SliceStable(tempos, func(i, j int) bool {
+		return tempos[i].quarter < tempos[j].quarter
+	})
+
+	notes := []timedNote{}
+	for _, part := range parts {
+		for _, n := range part {
+			start := xmlSeconds(tempos, n.start)
+
+			rdnd := n.rdnd
+			rdnd.Duration = xmlSeconds(tempos, n.end) - start
+			notes = append(notes, timedNote{start, rdnd})
+		}
+	}
+
+	return arrangeNotes(notes), nil
+}
+
+// Converting a real time in seconds to a point in a written file, in
+// divisions.
+func secondsToDivision(seconds float32) int {
+	divisions := float64(seconds) * xmlQuarterTempo / 60 * float64(xmlDivisions)
+	if divisions < 0 {
+		return 0
+	}
+
+	return int(math.Floor(divisions + 0.5))
+}
+
+// A written note value, lasting a number of divisions.
+type xmlValue struct {
+	divisions int
+	name      string
+	dots      int
+}
+
+// Splitting a length in divisions into the written values that make it up,
+// longest first, to be tied together. Plain and dotted values are used, and
+// anything too short for even the shortest value is added on to the value
+// before it, so that every note still has a type.
+func divisionsToValues(divisions int) []xmlValue {
+	values := []xmlValue{}
+	for remaining := divisions; remaining > 0; {
+		found := false
+		for _, v := range xmlNoteValues {
+			length := v.quarters * float64(xmlDivisions)
+
+			for _, dots := range []int{1, 0} {
+				l := length * (1 + 0.5*float64(dots))
+				if l == math.Floor(l) && int(l) <= remaining {
+					values = append(values, xmlValue{int(l), v.name, dots})
+					remaining -= int(l)
+					found = true
+					break
+				}
+			}
+
+			if found {
+				break
+			}
+		}
+
+		if !found {
+			if len(values) == 0 {
+				last := xmlNoteValues[len(xmlNoteValues)-1]
+				values = append(values, xmlValue{name: last.name})
+			}
+
+			values[len(values)-1].divisions += remaining
+			remaining = 0
+		}
+	}
+
+	return values
+}
+
+// A set of notes in one voice that start and end together, measured in
+// divisions.
+type xmlChord struct {
+	start, end int
+	notes      []synth.RawDelayedNoteData
+}
+
+// Splitting a part's notes into voices, where each voice is a run of chords
+// that don't overlap.
+func arrangeVoices(notes []synth.RawDelayedNoteData, starts []float32) [][]xmlChord {
+	voices := [][]xmlChord{}
+	for i, note := range notes {
+		start := secondsToDivision(starts[i])
+		end := secondsToDivision(starts[i] + note.Duration)
+		if end <= start {
+			end = start + 1
+		}
+
+		placed := false
+		for v := range voices {
+			last := &voices[v][len(voices[v])-1]
+			if last.start == start && last.end == end {
+				last.notes = append(last.notes, note)
+			} else if last.end <= start {
+				voices[v] = append(voices[v], xmlChord{start, end, []synth.RawDelayedNoteData{note}})
+			} else {
+				continue
+			}
+
+			placed = true
+			break
+		}
+
+		if !placed {
+			voices = append(voices, []xmlChord{xmlChord{start, end, []synth.RawDelayedNoteData{note}}})
+		}
+	}
+
+	return voices
+}
+
+// Constructing a note or rest element with a written value.
+func xmlNoteElement(voice int, value xmlValue) musicxml.Element {
+	return musicxml.Element{
+		XMLName:  xmlName("note"),
+		Duration: value.divisions,
+		Voice:    strconv.Itoa(voice),
+		Type:     value.name,
+		Dots:     make([]musicxml.Empty, value.dots),
+	}
+}
+
+// Constructing the elements of one voice in one measure, from the chords that
+// sound during it. Chords crossing a barline, or too long for a single written
+// value, are split and tied together. The first voice fills gaps with rests,
+// and the others skip over them.
+func voiceElements(voice int, chords []xmlChord, start, end int) ([]musicxml.Element, int, error) {
+	elements := []musicxml.Element{}
+	cursor := start
+
+	gap := func(until int) {
+		if until <= cursor {
+			return
+		}
+
+		if voice == 1 {
+			for _, value := range divisionsToValues(until - cursor) {
+				rest := xmlNoteElement(voice, value)
+				rest.Rest = &musicxml.Empty{}
+				elements = append(elements, rest)
+			}
+		} else {
+			elements = append(elements, musicxml.Element{
+				XMLName:  xmlName("forward"),
+				Duration: until - cursor,
+				Voice:    strconv.Itoa(voice),
+			})
+		}
+
+		cursor = until
+	}
+
+	for _, chord := range chords {
+		if chord.end <= start || chord.start >= end {
+			continue
+		}
+
+		from, to := chord.start, chord.end
+		if from < start {
+			from = start
+		}
+		if to > end {
+			to = end
+		}
+
+		gap(from)
+
+		values := divisionsToValues(to - from)
+		for v, value := range values {
+			ties := []musicxml.Tie{}
+			if v > 0 || from > chord.start {
+				ties = append(ties, musicxml.Tie{Type: "stop"})
+			}
+			if v < len(values)-1 || to < chord.end {
+				ties = append(ties, musicxml.Tie{Type: "start"})
+			}
+
+			for i, note := range chord.notes {
+				pitch, err := noteToXMLPitch(note.Note)
+				if err != nil {
+					return nil, 0, err
+				}
+
+				e := xmlNoteElement(voice, value)
+				e.Pitch = &pitch
+				if i > 0 {
+					e.Chord = &musicxml.Empty{}
+				}
+				if note.Volume != 0 {
+					dynamics := math.Floor(float64(note.Volume)*127/forteVelocity*10000+0.5) / 100
+					e.Dynamics = &dynamics
+				}
+				if len(ties) > 0 {
+					e.Ties = ties
+					e.Notations = []musicxml.Notations{musicxml.Notations{Tied: ties}}
+				}
+
+				elements = append(elements, e)
+			}
+		}
+
+		cursor = to
+	}
+
+	if voice == 1 {
+		gap(end)
+	}
+
+	return elements, cursor, nil
+}
+
+// Making an element name without a namespace.
+func xmlName(local string) xml.Name {
+	return xml.Name{Local: local}
+}
+
+// Writing out a partwise MusicXML file in 4/4 at a constant tempo, with a part
+// for every instrument used in the arrangement. Overlapping notes in a part are
+// written in separate voices.
+func (a MusicXMLArrangement) WriteNoteArrangement(writer io.Writer, notes []synth.RawDelayedNoteData) error {
+	instruments := []string{}
+	grouped := map[string][]synth.RawDelayedNoteData{}
+	starts := map[string][]float32{}
+
+	var time float32 = 0
+	for _, note := range notes {
+		time += note.Delay
+
+		if _, ok := grouped[note.Instrument]; !ok {
+			instruments = append(instruments, note.Instrument)
+		}
+
+		grouped[note.Instrument] = append(grouped[note.Instrument], note)
+		starts[note.Instrument] = append(starts[note.Instrument], time)
+	}
+
+	// Every part is given the same number of measures, enough to hold the last
+	// note to end.
+	measureLength := xmlMeasureQuarters * xmlDivisions
+	voices := map[string][][]xmlChord{}
+	measures := 1
+	for _, instrument := range instruments {
+		voices[instrument] = arrangeVoices(grouped[instrument], starts[instrument])
+		for _, voice := range voices[instrument] {
+			for _, chord := range voice {
+				if m := (chord.end + measureLength - 1) / measureLength; m > measures {
+					measures = m
+				}
+			}
+		}
+	}
+
+	score := musicxml.Score{}
+	for p, instrument := range instruments {
+		id := fmt.Sprintf("P%d", p+1)
+		score.PartList = append(score.PartList, musicxml.ScorePart{
+			ID:          id,
+			Name:        instrument,
+			Instruments: []musicxml.ScoreInstrument{musicxml.ScoreInstrument{ID: id + "-I1", Name: instrument}},
+		})
+
+		part := musicxml.Part{ID: id}
+		for m := 0; m < measures; m++ {
+			measure := musicxml.Measure{Number: strconv.Itoa(m + 1)}
+			if m == 0 {
+				measure.Elements = append(measure.Elements, musicxml.Element{
+					XMLName:   xmlName("attributes"),
+					Divisions: xmlDivisions,
+					Key:       &musicxml.Key{Fifths: 0},
+					Time:      &musicxml.Time{Beats: strconv.Itoa(xmlMeasureQuarters), BeatType: "4"},
+				})
+
+				if p == 0 {
+					measure.Elements = append(measure.Elements, musicxml.Element{
+						XMLName: xmlName("direction"),
+						DirectionTypes: []musicxml.DirectionType{musicxml.DirectionType{
+							Metronome: &musicxml.Metronome{BeatUnit: "quarter", PerMinute: strconv.FormatFloat(xmlQuarterTempo, 'f', -1, 64)},
+						}},
+						Sound: &musicxml.Sound{Tempo: xmlQuarterTempo},
+					})
+				}
+			}
+
+			start, end := m*measureLength, (m+1)*measureLength
+			cursor := start
+			for v, chords := range voices[instrument] {
+				elements, reached, err := voiceElements(v+1, chords, start, end)
+				if err != nil {
+					return err
+				} else if len(elements) == 0 {
+					continue
+				}
+
+				// Going back to the start of the measure for every voice after
+				// the first.
+				if cursor > start {
+					measure.Elements = append(measure.Elements, musicxml.Element{
+						XMLName:  xmlName("backup"),
+						Duration: cursor - start,
+					})
+				}
+
+				measure.Elements = append(measure.Elements, elements...)
+				cursor = reached
+			}
+
+			part.Measures = append(part.Measures, measure)
+		}
+
+		score.Parts = append(score.Parts, part)
+	}
+
+	return score.Write(writer)
+}
@@ -0,0 +1,90 @@
+package musicxml
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+)
+
+const (
+	version string = "4.0"
+	doctype string = `<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 4.0 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">`
+)
+
+// Constructing and returning a Score from the data contained in an io.Reader.
+func Read(reader io.Reader) (*Score, error) {
+	decoder := xml.NewDecoder(reader)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, errors.New("Missing MusicXML score.")
+		} else if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "score-partwise":
+			score := new(Score)
+			if err := decoder.DecodeElement(score, &start); err != nil {
+				return nil, err
+			}
+
+			return score, nil
+		case "score-timewise":
+			return nil, errors.New("Timewise MusicXML scores are not supported.")
+		default:
+			return nil, errors.New("Not a MusicXML score: " + start.Name.Local)
+		}
+	}
+}
+
+// Constructing and returning a Score from a file on disk.
+func ReadFile(path string) (*Score, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Writing a Score out to some io.Writer.
+func (s *Score) Write(writer io.Writer) error {
+	score := *s
+	if score.Version == "" {
+		score.Version = version
+	}
+
+	if _, err := io.WriteString(writer, xml.Header+doctype+"\n"); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(score); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
+
+// Writing a Score out to a file location on disk. Opens a file and calls
+// Write(..)
+func (s *Score) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return s.Write(file)
+}
@@ -0,0 +1,116 @@
+package musicxml
+
+import (
+	"encoding/xml"
+)
+
+// An element whose presence is all that matters, like <chord/>.
+type Empty struct{}
+
+// A partwise MusicXML score, where each part holds its own measures.
+type Score struct {
+	XMLName  xml.Name    `xml:"score-partwise"`
+	Version  string      `xml:"version,attr,omitempty"`
+	PartList []ScorePart `xml:"part-list>score-part"`
+	Parts    []Part      `xml:"part"`
+}
+
+// The description of a part in the part list.
+type ScorePart struct {
+	ID          string            `xml:"id,attr"`
+	Name        string            `xml:"part-name"`
+	Instruments []ScoreInstrument `xml:"score-instrument"`
+}
+
+// An instrument played in a part.
+type ScoreInstrument struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"instrument-name"`
+}
+
+// The measures of a single part.
+type Part struct {
+	ID       string    `xml:"id,attr"`
+	Measures []Measure `xml:"measure"`
+}
+
+// A single measure, holding its elements in the order they're written.
+type Measure struct {
+	Number   string    `xml:"number,attr"`
+	Elements []Element `xml:",any"`
+}
+
+// A single element inside of a measure. Which fields are used depends on the
+// name of the element: <attributes>, <note>, <backup>, <forward>, <direction>
+// or <sound>. Every other element is kept by name alone.
+type Element struct {
+	XMLName xml.Name
+
+	Divisions int   `xml:"divisions,omitempty"` // The divisions per quarter note for <attributes>.
+	Key       *Key  `xml:"key"`                 // The key signature for <attributes>.
+	Time      *Time `xml:"time"`                // The time signature for <attributes>.
+
+	Dynamics  *float64    `xml:"dynamics,attr,omitempty"` // The loudness of a <note>, as a percentage of forte, if it's given.
+	Grace     *Empty      `xml:"grace"`                   // Whether a <note> is a grace note, without a duration.
+	Cue       *Empty      `xml:"cue"`                     // Whether a <note> is a cue note, which isn't played.
+	Chord     *Empty      `xml:"chord"`                   // Whether a <note> starts with the note before it.
+	Pitch     *Pitch      `xml:"pitch"`                   // The pitch of a <note>.
+	Unpitched *Empty      `xml:"unpitched"`               // Whether a <note> is unpitched percussion.
+	Rest      *Empty      `xml:"rest"`                    // Whether a <note> is a rest.
+	Duration  int         `xml:"duration,omitempty"`      // The length in divisions of a <note>, <backup> or <forward>.
+	Ties      []Tie       `xml:"tie"`                     // The ties a <note> starts or stops.
+	Voice     string      `xml:"voice,omitempty"`         // The voice of a <note> or <forward>.
+	Type      string      `xml:"type,omitempty"`          // The written value of a <note>, like "quarter".
+	Dots      []Empty     `xml:"dot"`                     // The dots on the written value of a <note>.
+	Notations []Notations `xml:"notations"`               // The notations on a <note>.
+
+	DirectionTypes []DirectionType `xml:"direction-type"` // What a <direction> shows.
+	Sound          *Sound          `xml:"sound"`          // How a <direction> is played back.
+
+	Tempo float64 `xml:"tempo,attr,omitempty"` // The quarter notes per minute of a <sound>.
+}
+
+// A key signature, as a number of sharps, or flats when negative.
+type Key struct {
+	Fifths int `xml:"fifths"`
+}
+
+// A time signature.
+type Time struct {
+	Beats    string `xml:"beats"`
+	BeatType string `xml:"beat-type"`
+}
+
+// The pitch of a note, where Alter is in semitones and may be fractional.
+type Pitch struct {
+	Step   string  `xml:"step"`
+	Alter  float64 `xml:"alter,omitempty"`
+	Octave int     `xml:"octave"`
+}
+
+// A tie, of the type "start" or "stop".
+type Tie struct {
+	Type string `xml:"type,attr"`
+}
+
+// The notations on a note, of which only ties are kept.
+type Notations struct {
+	Tied []Tie `xml:"tied"`
+}
+
+// Something shown by a direction, of which only metronome marks are kept.
+type DirectionType struct {
+	Metronome *Metronome `xml:"metronome"`
+}
+
+// A metronome mark, like a dotted quarter at 60 per minute.
+type Metronome struct {
+	BeatUnit     string  `xml:"beat-unit"`
+	BeatUnitDots []Empty `xml:"beat-unit-dot"`
+	PerMinute    string  `xml:"per-minute"`
+}
+
+// How a direction is played back.
+type Sound struct {
+	Tempo float64 `xml:"tempo,attr,omitempty"`
+}
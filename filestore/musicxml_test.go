@@ -0,0 +1,189 @@
+package filestore
+
+import (
+	"bytes"
+	"github.com/crockeo/go-tuner/filestore/musicxml"
+	"github.com/crockeo/go-tuner/synth"
+	"math"
+	"strings"
+	"testing"
+)
+
+// A measure and a quarter of quarter notes, each with a different dynamics or
+// none.
+const testDynamicsScore string = `<?xml version="1.0" encoding="UTF-8"?>
+<score-partwise version="3.1">
+  <part-list>
+    <score-part id="P1"><part-name>Guitar</part-name></score-part>
+  </part-list>
+  <part id="P1">
+    <measure number="1">
+      <attributes><divisions>1</divisions></attributes>
+      <note><pitch><step>C</step><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+      <note dynamics="100"><pitch><step>D</step><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+      <note dynamics="50"><pitch><step>E</step><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+      <note dynamics="0"><pitch><step>F</step><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+      <note dynamics="140"><pitch><step>G</step><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+      <note dynamics="160"><pitch><step>A</step><octave>4</octave></pitch><duration>1</duration><type>quarter</type></note>
+    </measure>
+  </part>
+</score-partwise>
+`
+
+func TestMusicXMLDynamics(t *testing.T) {
+	notes, err := MusicXMLArrangement{}.ReadNoteArrangement(strings.NewReader(testDynamicsScore))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dynamics are scaled like MIDI velocities, with 100 standing for a
+	// velocity of 90. Notes without a dynamics play at full volume, and the
+	// silent F4 is left out.
+	want := []struct {
+		note   string
+		volume float32
+	}{
+		{"C4", 0},
+		{"D4", 90.0 / 127},
+		{"E4", 45.0 / 127},
+		{"G4", 126.0 / 127},
+		{"A4", 1},
+	}
+
+	if len(notes) != len(want) {
+		t.Fatalf("Expected %d notes, got %d: %v.", len(want), len(notes), notes)
+	}
+
+	for i, w := range want {
+		if notes[i].Note != w.note || math.Abs(float64(notes[i].Volume-w.volume)) > 1e-6 {
+			t.Errorf("Note %d: expected %s at volume %v, got %s at volume %v.", i, w.note, w.volume, notes[i].Note, notes[i].Volume)
+		}
+	}
+
+	// Writing the notes out and reading them back keeps every volume.
+	var buffer bytes.Buffer
+	if err := (MusicXMLArrangement{}).WriteNoteArrangement(&buffer, notes); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buffer.String(), `dynamics="0"`) {
+		t.Error("Expected notes without a volume to be written without a dynamics.")
+	}
+
+	// Writing is the inverse of reading, so dynamics above 100 come back out
+	// as they went in.
+	for _, dynamics := range []string{`dynamics="100"`, `dynamics="50"`, `dynamics="140"`, `dynamics="141.11"`} {
+		if !strings.Contains(buffer.String(), dynamics) {
+			t.Errorf("Expected a note to be written with %s.", dynamics)
+		}
+	}
+
+	again, err := MusicXMLArrangement{}.ReadNoteArrangement(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkVolumes(t, notes, again)
+}
+
+// Checking that two sets of notes have the same notes at the same volumes.
+func checkVolumes(t *testing.T, want, got []synth.RawDelayedNoteData) {
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d notes, got %d.", len(want), len(got))
+	}
+
+	for i := range want {
+		if got[i].Note != want[i].Note || math.Abs(float64(got[i].Volume-want[i].Volume)) > 1e-4 {
+			t.Errorf("Note %d: expected %s at volume %v, got %s at volume %v.", i, want[i].Note, want[i].Volume, got[i].Note, got[i].Volume)
+		}
+	}
+}
+
+func TestDivisionsToValues(t *testing.T) {
+	tests := []struct {
+		divisions int
+		values    []xmlValue
+	}{
+		{480, []xmlValue{{480, "quarter", 0}}},
+		{720, []xmlValue{{720, "quarter", 1}}},
+		{600, []xmlValue{{480, "quarter", 0}, {120, "16th", 0}}},
+		{1800, []xmlValue{{1440, "half", 1}, {360, "eighth", 1}}},
+		{1950, []xmlValue{{1920, "whole", 0}, {30, "64th", 0}}},
+		{500, []xmlValue{{480, "quarter", 0}, {20, "128th", 0}}},
+		{7, []xmlValue{{7, "128th", 0}}},
+	}
+
+	for _, test := range tests {
+		values := divisionsToValues(test.divisions)
+		if len(values) != len(test.values) {
+			t.Errorf("%d divisions: expected %v, got %v.", test.divisions, test.values, values)
+			continue
+		}
+
+		for i := range values {
+			if values[i] != test.values[i] {
+				t.Errorf("%d divisions: expected %v, got %v.", test.divisions, test.values, values)
+				break
+			}
+		}
+	}
+}
+
+func TestMusicXMLTiedValues(t *testing.T) {
+	// At 120 quarter notes a minute, a quarter and a 16th, then a gap of the
+	// same length, then a note too short for a 128th on its own.
+	notes := []synth.RawDelayedNoteData{
+		{Delay: 0, Note: "C4", Duration: 0.625, Instrument: "guitar"},
+		{Delay: 1.25, Note: "E4", Duration: 0.01, Instrument: "guitar"},
+	}
+
+	var buffer bytes.Buffer
+	if err := (MusicXMLArrangement{}).WriteNoteArrangement(&buffer, notes); err != nil {
+		t.Fatal(err)
+	}
+
+	score, err := musicxml.Read(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	written := []musicxml.Element{}
+	for _, e := range score.Parts[0].Measures[0].Elements {
+		if e.XMLName.Local != "note" {
+			continue
+		}
+
+		if e.Type == "" {
+			t.Errorf("Expected every note to have a type, but one lasting %d divisions doesn't.", e.Duration)
+		}
+
+		written = append(written, e)
+	}
+
+	// C4 as a tied quarter and 16th, two rests, E4, and the rest of the bar.
+	if len(written) < 5 {
+		t.Fatalf("Expected at least 5 notes and rests, got %d.", len(written))
+	}
+
+	if written[0].Type != "quarter" || written[1].Type != "16th" || len(written[0].Ties) != 1 || written[0].Ties[0].Type != "start" ||
+		len(written[1].Ties) != 1 || written[1].Ties[0].Type != "stop" {
+		t.Errorf("Expected C4 to be written as a quarter tied to a 16th.")
+	}
+
+	if written[2].Rest == nil || written[3].Rest == nil || written[2].Duration+written[3].Duration != 600 {
+		t.Errorf("Expected the gap to be written as two rests.")
+	}
+
+	if written[4].Type != "128th" || written[4].Duration != 10 || written[4].Ties != nil {
+		t.Errorf("Expected E4 to be written as a single 128th, got a %s lasting %d divisions.", written[4].Type, written[4].Duration)
+	}
+
+	again, err := MusicXMLArrangement{}.ReadNoteArrangement(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(again) != 2 || again[0].Duration != 0.625 || again[1].Delay != 1.25 {
+		t.Errorf("Expected the tied notes to be read back as they were written, got %v.", again)
+	}
+}
@@ -0,0 +1,208 @@
+package filestore
+
+import (
+	"bufio"
+	"errors"
+	"github.com/crockeo/go-tuner/synth"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Reading every line from a Scala file that isn't a comment.
+func readScalaLines(reader io.Reader) ([]string, error) {
+	lines := []string{}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if !strings.HasPrefix(line, "!") {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// Getting the first value from a line in a Scala file, ignoring anything after
+// it.
+func scalaField(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", errors.New("Expected a value, found a blank line.")
+	}
+
+	return fields[0], nil
+}
+
+// Parsing a pitch from a Scala scale into a ratio. Pitches with a '.' in them
+// are in cents, and anything else is a ratio like "3/2" or a whole number like
+// "2".
+func parseScalaPitch(pitch string) (float64, error) {
+	if strings.Contains(pitch, ".") {
+		cents, err := strconv.ParseFloat(pitch, 64)
+		if err != nil {
+			return 0, errors.New("Malformed pitch: " + pitch)
+		}
+
+		return math.Exp2(cents / 1200), nil
+	}
+
+	parts := strings.SplitN(pitch, "/", 2)
+	if len(parts) == 1 {
+		parts = append(parts, "1")
+	}
+
+	num, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, errors.New("Malformed pitch: " + pitch)
+	}
+
+	den, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || den == 0 {
+		return 0, errors.New("Malformed pitch: " + pitch)
+	}
+
+	return float64(num) / float64(den), nil
+}
+
+// Reading a synth.Scale from a Scala .scl file.
+func ReadScale(reader io.Reader) (synth.Scale, error) {
+	lines, err := readScalaLines(reader)
+	if err != nil {
+		return synth.Scale{}, err
+	}
+
+	if len(lines) < 2 {
+		return synth.Scale{}, errors.New("Scale files need a description and a number of notes.")
+	}
+
+	field, err := scalaField(lines[1])
+	if err != nil {
+		return synth.Scale{}, err
+	}
+
+	count, err := strconv.Atoi(field)
+	if err != nil || count < 1 {
+		return synth.Scale{}, errors.New("Malformed number of notes: " + lines[1])
+	}
+
+	if len(lines)-2 < count {
+		return synth.Scale{}, errors.New("Scale file has fewer notes than it says.")
+	}
+
+	scale := synth.Scale{
+		Description: strings.TrimSpace(lines[0]),
+		Degrees:     make([]float64, count),
+	}
+
+	for i := range scale.Degrees {
+		field, err := scalaField(lines[i+2])
+		if err != nil {
+			return synth.Scale{}, err
+		}
+
+		if scale.Degrees[i], err = parseScalaPitch(field); err != nil {
+			return synth.Scale{}, err
+		}
+	}
+
+	return scale, scale.Validate()
+}
+
+// Reading a synth.KeyboardMapping from a Scala .kbm file. Keys left off of the
+// end of the mapping are unmapped, as are keys marked with an 'x'.
+func ReadKeyboardMapping(reader io.Reader) (synth.KeyboardMapping, error) {
+	lines, err := readScalaLines(reader)
+	if err != nil {
+		return synth.KeyboardMapping{}, err
+	}
+
+	values := []string{}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		field, _ := scalaField(line)
+		values = append(values, field)
+	}
+
+	if len(values) < 7 {
+		return synth.KeyboardMapping{}, errors.New("Keyboard mapping files need 7 values before their keys.")
+	}
+
+	header := make([]int, 7)
+	for i := range header {
+		if i == 5 {
+			continue
+		}
+
+		if header[i], err = strconv.Atoi(values[i]); err != nil {
+			return synth.KeyboardMapping{}, errors.New("Malformed keyboard mapping value: " + values[i])
+		}
+	}
+
+	frequency, err := strconv.ParseFloat(values[5], 64)
+	if err != nil {
+		return synth.KeyboardMapping{}, errors.New("Malformed reference frequency: " + values[5])
+	}
+
+	// Scala numbers notes the same way as MIDI.
+	km := synth.KeyboardMapping{
+		Size:         header[0],
+		First:        header[1] - midiNoteOffset,
+		Last:         header[2] - midiNoteOffset,
+		Middle:       header[3] - midiNoteOffset,
+		Reference:    header[4] - midiNoteOffset,
+		Frequency:    frequency,
+		OctaveDegree: header[6],
+	}
+
+	if km.Size < 0 {
+		return synth.KeyboardMapping{}, errors.New("Keyboard mappings can't have a negative size.")
+	}
+
+	keys := values[7:]
+	if len(keys) > km.Size {
+		return synth.KeyboardMapping{}, errors.New("Keyboard mapping file has more keys than it says.")
+	}
+
+	km.Keys = make([]int, km.Size)
+	for i := range km.Keys {
+		km.Keys[i] = -1
+		if i >= len(keys) || keys[i] == "x" {
+			continue
+		}
+
+		if km.Keys[i], err = strconv.Atoi(keys[i]); err != nil || km.Keys[i] < 0 {
+			return synth.KeyboardMapping{}, errors.New("Malformed key: " + keys[i])
+		}
+	}
+
+	return km, km.Validate()
+}
+
+// Loading a synth.Scale from a Scala .scl file on disk.
+func LoadScale(path string) (synth.Scale, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return synth.Scale{}, errors.New("Could not open \"" + path + "\".")
+	}
+	defer file.Close()
+
+	return ReadScale(file)
+}
+
+// Loading a synth.KeyboardMapping from a Scala .kbm file on disk.
+func LoadKeyboardMapping(path string) (synth.KeyboardMapping, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return synth.KeyboardMapping{}, errors.New("Could not open \"" + path + "\".")
+	}
+	defer file.Close()
+
+	return ReadKeyboardMapping(file)
+}
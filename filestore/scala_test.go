@@ -0,0 +1,155 @@
+package filestore
+
+import (
+	"github.com/crockeo/go-tuner/synth"
+	"math"
+	"strings"
+	"testing"
+)
+
+// A five note scale with degrees given both in cents and as ratios.
+const testScale string = `! test.scl
+!
+ Five notes to the octave
+ 5
+!
+ 150.0
+ 5/4
+ 700.0 cents, a tempered fifth
+ 5/3
+ 2
+`
+
+// A six key mapping, starting at C4 with A4 at 440Hz, that leaves its second
+// key unmapped with an 'x' and its last by leaving it off of the list.
+const testKeyboardMapping string = `! test.kbm
+6
+60
+84
+60
+69
+440.0
+5
+! Keys
+0
+x
+1
+2
+3
+`
+
+func TestReadScale(t *testing.T) {
+	scale, err := ReadScale(strings.NewReader(testScale))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if scale.Description != "Five notes to the octave" {
+		t.Errorf("Expected the description to be read, got \"%s\".", scale.Description)
+	}
+
+	want := []float64{1.0905077, 1.25, 1.4983071, 5.0 / 3.0, 2}
+	if len(scale.Degrees) != len(want) {
+		t.Fatalf("Expected %d degrees, got %d.", len(want), len(scale.Degrees))
+	}
+
+	for i := range want {
+		if math.Abs(scale.Degrees[i]-want[i]) > 1e-6 {
+			t.Errorf("Degree %d: expected %v, got %v.", i+1, want[i], scale.Degrees[i])
+		}
+	}
+}
+
+func TestReadScaleErrors(t *testing.T) {
+	tests := []string{
+		"No notes\n",
+		"Too few notes\n3\n3/2\n2\n",
+		"Dividing by zero\n1\n3/0\n",
+		"Not a number\n1\nfifth\n",
+		"Negative\n1\n-3/2\n",
+	}
+
+	for _, test := range tests {
+		if _, err := ReadScale(strings.NewReader(test)); err == nil {
+			t.Errorf("Expected an error reading %q.", test)
+		}
+	}
+}
+
+func TestReadKeyboardMapping(t *testing.T) {
+	km, err := ReadKeyboardMapping(strings.NewReader(testKeyboardMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// MIDI note 60 is C4, which synth.NoteToInt numbers 48.
+	want := synth.KeyboardMapping{
+		Size:         6,
+		First:        48,
+		Last:         72,
+		Middle:       48,
+		Reference:    57,
+		Frequency:    440,
+		OctaveDegree: 5,
+		Keys:         []int{0, -1, 1, 2, 3, -1},
+	}
+
+	if km.Size != want.Size || km.First != want.First || km.Last != want.Last || km.Middle != want.Middle ||
+		km.Reference != want.Reference || km.Frequency != want.Frequency || km.OctaveDegree != want.OctaveDegree {
+		t.Errorf("Expected %+v, got %+v.", want, km)
+	}
+
+	for i := range want.Keys {
+		if i >= len(km.Keys) || km.Keys[i] != want.Keys[i] {
+			t.Fatalf("Expected keys %v, got %v.", want.Keys, km.Keys)
+		}
+	}
+}
+
+func TestScalaTuning(t *testing.T) {
+	scale, err := ReadScale(strings.NewReader(testScale))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	km, err := ReadKeyboardMapping(strings.NewReader(testKeyboardMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tuning, err := synth.NewScaleTuning(scale, km)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A4 is the fourth key of the second repeat, so it plays degree 5 + 2 at
+	// a ratio of 2 * 5/4 over C4, which leaves C4 at 440 / 2.5 = 176Hz.
+	tests := []struct {
+		note      string
+		frequency float64
+	}{
+		{"B3", 0},          // Below the first mapped note.
+		{"C4", 176},        // The first degree.
+		{"C#4", 0},         // An 'x' key.
+		{"D4", 191.929361}, // 176 * 2^(150/1200)
+		{"D#4", 220},       // 176 * 5/4
+		{"E4", 263.702045}, // 176 * 2^(700/1200)
+		{"F4", 0},          // Left off of the key list.
+		{"F#4", 352},       // The first degree, an octave up.
+		{"G#4", 383.858723},
+		{"A4", 440},
+		{"C5", 704},
+		{"C#5", 0}, // Above the last mapped note.
+	}
+
+	for _, test := range tests {
+		note, err := synth.NoteToInt(test.note)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f := float64(tuning.Frequency(note)); math.Abs(f-test.frequency) > 1e-3 {
+			t.Errorf("%s: expected %vHz, got %vHz.", test.note, test.frequency, f)
+		}
+	}
+}
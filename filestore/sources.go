@@ -32,8 +32,12 @@ func DecideFormat(extension string) (ArrangementFormat, error) {
 		return JSONArrangement{}, nil
 	case ".mid":
 		return MIDIArrangement{}, nil
+	case ".musicxml", ".xml":
+		return MusicXMLArrangement{}, nil
 	case ".txt":
 		return TextArrangement{}, nil
+	case ".wav":
+		return WAVArrangement{}, nil
 	default:
 		return nil, errors.New("Undecidable extension.")
 	}
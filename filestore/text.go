@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/crockeo/go-tuner/synth"
 	"io"
+	"strings"
 )
 
 // Dealing with snyth.RawDelayedNoteData from flat plaintext files in the legacy
@@ -14,24 +15,22 @@ type TextArrangement struct{}
 
 func (a TextArrangement) ReadNoteArrangement(reader io.Reader) ([]synth.RawDelayedNoteData, error) {
 	parseLine := func(line string) (synth.RawDelayedNoteData, error) {
-		var delay float32
-		var note string
-		var duration float32
-		var instrument string
+		var rdnd synth.RawDelayedNoteData
+		fields := []interface{}{&rdnd.Delay, &rdnd.Note, &rdnd.Duration, &rdnd.Instrument, &rdnd.Volume, &rdnd.Pan}
 
-		n, err := fmt.Sscanf(line, "%f %s %f %s\n", &delay, &note, &duration, &instrument)
-		if n != 4 || err != nil {
+		// The volume and pan are optional, and can be left off of the end of a
+		// line.
+		count := len(strings.Fields(line))
+		if count < 4 || count > len(fields) {
 			return synth.RawDelayedNoteData{}, errors.New("Malformed line: \"" + line + "\"")
 		}
 
-		return synth.RawDelayedNoteData{
-			delay,
-			note,
-			duration,
-			instrument,
-		}, nil
+		n, err := fmt.Sscan(line, fields[:count]...)
+		if n != count || err != nil {
+			return synth.RawDelayedNoteData{}, errors.New("Malformed line: \"" + line + "\"")
+		}
 
-		return synth.RawDelayedNoteData{}, nil
+		return rdnd, nil
 	}
 
 	realReader := bufio.NewReader(reader)
@@ -66,7 +65,16 @@ func (a TextArrangement) ReadNoteArrangement(reader io.Reader) ([]synth.RawDelay
 
 func (a TextArrangement) WriteNoteArrangement(writer io.Writer, notes []synth.RawDelayedNoteData) error {
 	formatLine := func(note synth.RawDelayedNoteData) string {
-		return fmt.Sprintf("%f %s %f %s\n", note.Delay, note.Note, note.Duration, note.Instrument)
+		line := fmt.Sprintf("%f %s %f %s", note.Delay, note.Note, note.Duration, note.Instrument)
+		if note.Volume != 0 || note.Pan != 0 {
+			line += fmt.Sprintf(" %f", note.Volume)
+		}
+
+		if note.Pan != 0 {
+			line += fmt.Sprintf(" %f", note.Pan)
+		}
+
+		return line + "\n"
 	}
 
 	for _, v := range notes {
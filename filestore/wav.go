@@ -0,0 +1,100 @@
+package filestore
+
+import (
+	"errors"
+	"github.com/crockeo/go-tuner/filestore/wav"
+	"github.com/crockeo/go-tuner/synth"
+	"github.com/crockeo/go-tuner/tuner"
+	"io"
+	"math"
+)
+
+// Rounding a time to the nearest millisecond, so transcriptions read cleanly.
+func roundMillis(seconds float32) float32 {
+	return float32(math.Floor(float64(seconds)*1000+0.5) / 1000)
+}
+
+// Dealing with synth.RawDelayedNoteData from a WAV recording. Reading
+// transcribes the recording with a tuner.Transcriber, which works best on a
+// single melody, and writing synthesizes the notes offline.
+//
+// Notes played over others that are still ringing are usually heard right,
+// but chords come out as a single note, which can be an octave or more under
+// any of the notes in them - together they only repeat at a period they all
+// share, like C2 for G3 and C4.
+type WAVArrangement struct {
+	Settings synth.Settings // The settings to synthesize with, or the zero value for synth.DefaultSettings.
+	Encoding wav.Encoding   // The encoding of written samples.
+}
+
+func (a WAVArrangement) ReadNoteArrangement(reader io.Reader) ([]synth.RawDelayedNoteData, error) {
+	r, err := wav.NewReader(reader)
+	if err != nil {
+		return []synth.RawDelayedNoteData{}, err
+	}
+
+	// Mixing every channel down to mono.
+	samples := []float32{}
+	for {
+		frame, err := r.ReadFrame()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return []synth.RawDelayedNoteData{}, err
+		}
+
+		var sample float32
+		for _, s := range frame {
+			sample += s
+		}
+		samples = append(samples, sample/float32(len(frame)))
+	}
+
+	notes := tuner.NewTranscriber(r.Format().SampleRate).Transcribe(samples)
+
+	rdnds := make([]synth.RawDelayedNoteData, len(notes))
+	var last float32
+	for i, n := range notes {
+		start := roundMillis(n.Start)
+
+		rdnds[i] = synth.RawDelayedNoteData{
+			Delay:      roundMillis(start - last),
+			Note:       synth.NoteToString(n.Note),
+			Duration:   roundMillis(n.Duration),
+			Instrument: "guitar",
+			Volume:     float32(math.Floor(float64(n.Volume)*100+0.5) / 100),
+		}
+
+		last = start
+	}
+
+	return rdnds, nil
+}
+
+func (a WAVArrangement) WriteNoteArrangement(writer io.Writer, notes []synth.RawDelayedNoteData) error {
+	// The sizes in the header can only be filled in once the notes have
+	// finished, so the header has to be gone back to.
+	seeker, ok := writer.(io.WriteSeeker)
+	if !ok {
+		return errors.New("WAV arrangements can only be written to something that can seek.")
+	}
+
+	settings := a.Settings
+	if settings == (synth.Settings{}) {
+		settings = synth.DefaultSettings()
+	}
+
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	na, err := synth.MakeNoteArrangement(notes)
+	if err != nil {
+		return err
+	}
+
+	pd := synth.NewPrimaryDriver(*na)
+	pd.ApplySettings(settings)
+
+	return synth.RenderWAV(pd, settings.SampleRate, seeker, a.Encoding)
+}
@@ -0,0 +1,336 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+const (
+	formatPCM        uint16 = 1      // The WAVE_FORMAT_PCM format tag.
+	formatFloat      uint16 = 3      // The WAVE_FORMAT_IEEE_FLOAT format tag.
+	formatExtensible uint16 = 0xFFFE // The WAVE_FORMAT_EXTENSIBLE format tag.
+)
+
+// Type Writer streams frames of samples out to a WAV file. Because the chunk
+// sizes are only known once frames have been written, the header is patched in
+// Flush and Close.
+type Writer struct {
+	seeker io.WriteSeeker
+	writer *bufio.Writer
+	format Format
+
+	frames     uint32 // The number of frames written so far.
+	factOffset int64  // Where the fact chunk's frame count lives, or 0.
+	dataOffset int64  // Where the data chunk's size lives.
+}
+
+// Writing a series of values out in little endian.
+func writeLE(writer io.Writer, values ...interface{}) error {
+	for _, v := range values {
+		if err := binary.Write(writer, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Creating a new Writer and writing out the WAV header to the underlying
+// io.WriteSeeker.
+func NewWriter(seeker io.WriteSeeker, format Format) (*Writer, error) {
+	if format.Channels < 1 || format.SampleRate < 1 {
+		return nil, errors.New("Invalid WAV format.")
+	} else if format.Encoding != PCM16 && format.Encoding != Float32 {
+		return nil, errors.New("WAV files can only be written as PCM16 or Float32.")
+	}
+
+	w := &Writer{
+		seeker: seeker,
+		writer: bufio.NewWriter(seeker),
+		format: format,
+	}
+
+	tag := formatPCM
+	fmtLength := uint32(16)
+	if format.Encoding == Float32 {
+		tag = formatFloat
+		fmtLength = 18
+	}
+
+	blockAlign := uint16(format.FrameSize())
+	bits := uint16(format.Encoding.SampleSize() * 8)
+
+	err := writeLE(w.writer,
+		[]byte("RIFF"), uint32(0), []byte("WAVE"),
+		[]byte("fmt "), fmtLength,
+		tag,
+		uint16(format.Channels),
+		uint32(format.SampleRate),
+		uint32(format.SampleRate)*uint32(blockAlign),
+		blockAlign,
+		bits)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := int64(12 + 8 + 16)
+	if format.Encoding == Float32 {
+		// Non-PCM formats carry an (empty) extension size and a fact chunk with
+		// the number of frames.
+		if err := writeLE(w.writer, uint16(0), []byte("fact"), uint32(4), uint32(0)); err != nil {
+			return nil, err
+		}
+
+		w.factOffset = offset + 2 + 8
+		offset += 2 + 12
+	}
+
+	if err := writeLE(w.writer, []byte("data"), uint32(0)); err != nil {
+		return nil, err
+	}
+	w.dataOffset = offset + 4
+
+	return w, nil
+}
+
+// Writing a single frame of samples, one per channel. Samples are expected to
+// be in the range [-1, 1], and are clipped to it for integer encodings.
+func (w *Writer) WriteFrame(frame []float32) error {
+	if len(frame) != w.format.Channels {
+		return errors.New("Frame does not match the number of channels.")
+	}
+
+	for _, s := range frame {
+		var err error
+		switch w.format.Encoding {
+		case Float32:
+			err = binary.Write(w.writer, binary.LittleEndian, math.Float32bits(s))
+		default:
+			if s > 1 {
+				s = 1
+			} else if s < -1 {
+				s = -1
+			}
+
+			err = binary.Write(w.writer, binary.LittleEndian, int16(s*math.MaxInt16))
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	w.frames++
+	return nil
+}
+
+// Writing out any buffered frames and filling in the chunk sizes in the header,
+// so that the file is valid up to the last frame written.
+func (w *Writer) Flush() error {
+	if err := w.writer.Flush(); err != nil {
+		return err
+	}
+
+	// Every encoding has an even sample size, so the data chunk never needs a
+	// padding byte.
+	dataLength := w.frames * uint32(w.format.FrameSize())
+	end := w.dataOffset + 4 + int64(dataLength)
+
+	patch := func(offset int64, value uint32) error {
+		if _, err := w.seeker.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+
+		return writeLE(w.seeker, value)
+	}
+
+	if err := patch(4, uint32(end-8)); err != nil {
+		return err
+	}
+
+	if err := patch(w.dataOffset, dataLength); err != nil {
+		return err
+	}
+
+	if w.factOffset != 0 {
+		if err := patch(w.factOffset, w.frames); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.seeker.Seek(end, io.SeekStart)
+	return err
+}
+
+// Finishing the WAV file. Close does not close the underlying io.WriteSeeker.
+func (w *Writer) Close() error {
+	return w.Flush()
+}
+
+// Type Reader reads frames of samples from a WAV file, converting every
+// encoding to floats in the range [-1, 1].
+type Reader struct {
+	reader    *bufio.Reader
+	format    Format
+	remaining uint32 // The number of bytes left in the data chunk.
+	buffer    []byte
+}
+
+// Working out the Encoding of a fmt chunk from its format tag and bits per
+// sample.
+func decideEncoding(tag uint16, bits uint16) (Encoding, error) {
+	switch {
+	case tag == formatPCM && bits == 8:
+		return PCM8, nil
+	case tag == formatPCM && bits == 16:
+		return PCM16, nil
+	case tag == formatPCM && bits == 24:
+		return PCM24, nil
+	case tag == formatPCM && bits == 32:
+		return PCM32, nil
+	case tag == formatFloat && bits == 32:
+		return Float32, nil
+	case tag == formatFloat && bits == 64:
+		return Float64, nil
+	default:
+		return 0, errors.New("Unsupported WAV encoding.")
+	}
+}
+
+// Creating a new Reader, reading the WAV header up to the start of the
+// samples. Chunks other than fmt and data are skipped.
+func NewReader(reader io.Reader) (*Reader, error) {
+	r := &Reader{reader: bufio.NewReader(reader)}
+
+	var riff, wave [4]byte
+	var length uint32
+	if err := binary.Read(r.reader, binary.LittleEndian, &riff); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r.reader, binary.LittleEndian, &length); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r.reader, binary.LittleEndian, &wave); err != nil {
+		return nil, err
+	}
+
+	if string(riff[:]) != "RIFF" || string(wave[:]) != "WAVE" {
+		return nil, errors.New("Not a WAV file.")
+	}
+
+	haveFormat := false
+	for {
+		var id [4]byte
+		var size uint32
+		if err := binary.Read(r.reader, binary.LittleEndian, &id); err != nil {
+			return nil, errors.New("WAV file has no data chunk.")
+		}
+		if err := binary.Read(r.reader, binary.LittleEndian, &size); err != nil {
+			return nil, err
+		}
+
+		switch string(id[:]) {
+		case "fmt ":
+			if size < 16 {
+				return nil, errors.New("Malformed WAV fmt chunk.")
+			}
+
+			// Chunks are padded to an even length.
+			chunk := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r.reader, chunk); err != nil {
+				return nil, err
+			}
+
+			tag := binary.LittleEndian.Uint16(chunk[0:])
+			bits := binary.LittleEndian.Uint16(chunk[14:])
+
+			// Extensible formats keep the real format tag at the start of
+			// their sub-format GUID.
+			if tag == formatExtensible {
+				if size < 40 {
+					return nil, errors.New("Malformed WAV fmt chunk.")
+				}
+				tag = binary.LittleEndian.Uint16(chunk[24:])
+			}
+
+			encoding, err := decideEncoding(tag, bits)
+			if err != nil {
+				return nil, err
+			}
+
+			r.format = Format{
+				Encoding:   encoding,
+				Channels:   int(binary.LittleEndian.Uint16(chunk[2:])),
+				SampleRate: int(binary.LittleEndian.Uint32(chunk[4:])),
+			}
+
+			if r.format.Channels < 1 || r.format.SampleRate < 1 {
+				return nil, errors.New("Invalid WAV format.")
+			}
+
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, errors.New("WAV data chunk comes before its fmt chunk.")
+			}
+
+			r.remaining = size
+			r.buffer = make([]byte, r.format.FrameSize())
+
+			return r, nil
+		default:
+			if _, err := io.CopyN(ioutil.Discard, r.reader, int64(size+size%2)); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// Getting the format of the WAV file being read.
+func (r *Reader) Format() Format {
+	return r.format
+}
+
+// Reading a single frame of samples, one per channel. Returns io.EOF once
+// every frame has been read.
+func (r *Reader) ReadFrame() ([]float32, error) {
+	if r.remaining < uint32(len(r.buffer)) {
+		return nil, io.EOF
+	}
+
+	if _, err := io.ReadFull(r.reader, r.buffer); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+
+		return nil, err
+	}
+	r.remaining -= uint32(len(r.buffer))
+
+	size := r.format.Encoding.SampleSize()
+	frame := make([]float32, r.format.Channels)
+	for i := range frame {
+		b := r.buffer[i*size:]
+
+		switch r.format.Encoding {
+		case PCM8:
+			frame[i] = (float32(b[0]) - 128) / 128
+		case PCM16:
+			frame[i] = float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		case PCM24:
+			frame[i] = float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		case PCM32:
+			frame[i] = float32(float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31))
+		case Float32:
+			frame[i] = math.Float32frombits(binary.LittleEndian.Uint32(b))
+		case Float64:
+			frame[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}
+	}
+
+	return frame, nil
+}
@@ -0,0 +1,41 @@
+package wav
+
+// The encoding of the samples inside of a WAV file.
+type Encoding int
+
+const (
+	PCM16   Encoding = iota // 16-bit signed integer samples.
+	Float32                 // 32-bit IEEE floating point samples.
+	PCM8                    // 8-bit unsigned integer samples. Only supported by Reader.
+	PCM24                   // 24-bit signed integer samples. Only supported by Reader.
+	PCM32                   // 32-bit signed integer samples. Only supported by Reader.
+	Float64                 // 64-bit IEEE floating point samples. Only supported by Reader.
+)
+
+// The format information for a WAV file.
+type Format struct {
+	Encoding   Encoding
+	Channels   int
+	SampleRate int
+}
+
+// The number of bytes a single sample takes up in a given encoding.
+func (e Encoding) SampleSize() int {
+	switch e {
+	case PCM8:
+		return 1
+	case PCM24:
+		return 3
+	case Float32, PCM32:
+		return 4
+	case Float64:
+		return 8
+	default:
+		return 2
+	}
+}
+
+// The number of bytes a single frame (one sample for every channel) takes up.
+func (f Format) FrameSize() int {
+	return f.Encoding.SampleSize() * f.Channels
+}
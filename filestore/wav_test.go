@@ -0,0 +1,83 @@
+package filestore
+
+import (
+	"github.com/crockeo/go-tuner/synth"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+)
+
+// A note and the absolute time it starts at.
+type startedNote struct {
+	start float32
+	note  string
+}
+
+// Turning the delays of a set of notes into absolute start times.
+func noteStarts(rdnds []synth.RawDelayedNoteData) []startedNote {
+	starts := make([]startedNote, len(rdnds))
+
+	var time float32
+	for i, rdnd := range rdnds {
+		time += rdnd.Delay
+		starts[i] = startedNote{time, rdnd.Note}
+	}
+
+	return starts
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	song, err := os.Open("../res/songs/scale.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer song.Close()
+
+	notes, err := TextArrangement{}.ReadNoteArrangement(song)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := ioutil.TempFile("", "scale*.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := (WAVArrangement{}).WriteNoteArrangement(file, notes); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	heard, err := WAVArrangement{}.ReadNoteArrangement(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every note overlaps the one before it, so each one is heard over the
+	// last one ringing. The scale ends on a chord, which is heard as one of
+	// its notes.
+	want := noteStarts(notes)
+	got := noteStarts(heard)
+	chord := len(want) - 2
+
+	if len(got) < chord+1 {
+		t.Fatalf("Expected at least %d notes, got %d: %v.", chord+1, len(got), got)
+	}
+
+	for i := 0; i <= chord; i++ {
+		if math.Abs(float64(got[i].start-want[i].start)) > 0.02 {
+			t.Errorf("Note %d: expected a start at %vs, got %vs.", i, want[i].start, got[i].start)
+		}
+
+		if got[i].note != want[i].note && (i < chord || got[i].note != want[i+1].note) {
+			t.Errorf("Note %d: expected %s, got %s.", i, want[i].note, got[i].note)
+		}
+	}
+}
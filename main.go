@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/crockeo/go-tuner/convert"
 	"github.com/crockeo/go-tuner/filestore"
+	"github.com/crockeo/go-tuner/filestore/wav"
 	"github.com/crockeo/go-tuner/server"
 	"github.com/crockeo/go-tuner/synth"
 	"github.com/crockeo/go-tuner/visualize"
@@ -17,14 +18,21 @@ func printHelp() {
 	fmt.Println(" go-tuner file <file/path>")
 	fmt.Println(" go-tuner visualize <file/path>")
 	fmt.Println(" go-tuner convert <original/file/path> <new/file/path>")
+	fmt.Println(" go-tuner render <file/path> <wav/path> [16|32]")
+	fmt.Println(" go-tuner tune [wav/path]")
+	fmt.Println()
+	fmt.Println("Options:")
+	flags.SetOutput(os.Stdout)
+	flags.PrintDefaults()
 }
 
-// Handling er
+// Handling errors sent from the server and synth. Errors go to stderr so they
+// don't end up in the audio when using the stdout sink.
 func handleErrors(errChannel chan error) {
 	for {
 		err := <-errChannel
 		if err != nil {
-			fmt.Println(err.Error())
+			fmt.Fprintln(os.Stderr, err.Error())
 		}
 	}
 }
@@ -36,6 +44,50 @@ func main() {
 		return
 	}
 
+	flags.Usage = printHelp
+	args, err := parseArgs(os.Args[2:])
+	if err != nil {
+		return
+	}
+
+	if _, err := os.Stat(defaultInstruments); err == nil {
+		if err := filestore.LoadInstruments(defaultInstruments); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return
+		}
+	}
+
+	if *instrumentsFlag != "" {
+		if err := filestore.LoadInstruments(*instrumentsFlag); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return
+		}
+	}
+
+	sink, err := parseSink(*sinkFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+
+	settings, err := parseSettings()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+
+	playback, err := parsePlayback()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return
+	}
+
+	// Loop regions are given in the time of the song as it's written, so they
+	// have to follow the tempo.
+	loop := loopFlag.Region
+	loop.Start = playback.ScaleTime(loop.Start)
+	loop.End = playback.ScaleTime(loop.End)
+
 	errChannel := make(chan error, 8)
 	defer close(errChannel)
 	go handleErrors(errChannel)
@@ -43,58 +95,133 @@ func main() {
 	if os.Args[1] == "server" {
 		noteChannel := make(chan synth.DelayedNoteData, 32)
 		defer close(noteChannel)
-		go server.Start(errChannel, noteChannel)
 
-		err := synth.StartSynth(noteChannel)
-		if err != nil {
-			fmt.Println(err.Error())
-		}
+		quitChannel := make(chan bool)
+		defer close(quitChannel)
+
+		controller := synth.StartSynthAsync(sink, settings, noteChannel, quitChannel, errChannel)
+		go server.Start(errChannel, noteChannel, controller)
+
+		<-controller.Done()
 	} else if os.Args[1] == "file" {
-		if len(os.Args) != 3 {
+		if len(args) != 1 {
 			printHelp()
 			return
 		}
 
-		na, err := filestore.LoadNoteArrangement(os.Args[2])
+		na, err := filestore.LoadNoteArrangementWith(args[0], playback)
 		if err != nil {
-			fmt.Println(err.Error())
+			fmt.Fprintln(os.Stderr, err.Error())
 			return
 		}
 
-		if err = synth.StartSynthWith(na, make(chan synth.DelayedNoteData), true); err != nil {
-			fmt.Println(err.Error())
+		quitChannel := make(chan bool)
+		defer close(quitChannel)
+
+		controller := synth.StartSynthAsyncWith(sink, settings, na, make(chan synth.DelayedNoteData), quitChannel, errChannel, true)
+		if loopFlag.Enabled {
+			controller.SetLoop(loop)
+		}
+
+		select {
+		case <-quitChannel:
+		case <-controller.Done():
 		}
 	} else if os.Args[1] == "visualize" {
-		if len(os.Args) != 3 {
+		if len(args) != 1 {
 			printHelp()
 			return
 		}
 
+		na, err := filestore.LoadNoteArrangementWith(args[0], playback)
+		if err != nil {
+			fmt.Println("Could not load song: " + err.Error())
+			return
+		}
+
 		quitChannel := make(chan bool)
 		defer close(quitChannel)
 
-		noteChannel := make(chan synth.DelayedNoteData, 32)
-		go synth.StartSynthAsync(noteChannel, quitChannel, errChannel)
-
-		na, err := filestore.LoadNoteArrangement(os.Args[2])
-		if err != nil {
-			fmt.Println("Could not load song: " + err.Error())
+		controller := synth.StartSynthAsyncWith(sink, settings, na, make(chan synth.DelayedNoteData), quitChannel, errChannel, false)
+		if loopFlag.Enabled {
+			controller.SetLoop(loop)
 		}
 
-		if err = visualize.RunVisualization(na, noteChannel); err != nil {
+		if err = visualize.RunVisualization(na, controller); err != nil {
 			fmt.Println("Visualize error: " + err.Error())
 		}
 
-		quitChannel <- true
+		controller.Stop()
 	} else if os.Args[1] == "convert" {
-		if len(os.Args) != 4 {
+		if len(args) != 2 {
 			printHelp()
 			return
 		}
 
-		if err := convert.ConvertAuto(os.Args[2], os.Args[3]); err != nil {
+		if err := convert.ConvertAuto(args[0], args[1]); err != nil {
 			fmt.Println("Failed to convert files: " + err.Error())
 		}
+	} else if os.Args[1] == "render" {
+		if len(args) != 2 && len(args) != 3 {
+			printHelp()
+			return
+		}
+
+		encoding := wav.PCM16
+		if len(args) == 3 {
+			switch args[2] {
+			case "16":
+				encoding = wav.PCM16
+			case "32":
+				encoding = wav.Float32
+			default:
+				printHelp()
+				return
+			}
+		}
+
+		if loopFlag.Enabled && loop.Count == synth.LoopForever {
+			fmt.Fprintln(os.Stderr, "Can't render a loop that never ends, give --loop a count.")
+			return
+		}
+
+		na, err := filestore.LoadNoteArrangementWith(args[0], playback)
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+
+		file, err := os.Create(args[1])
+		if err != nil {
+			fmt.Println(err.Error())
+			return
+		}
+		defer file.Close()
+
+		pd := synth.NewPrimaryDriver(*na)
+		pd.ApplySettings(settings)
+		if loopFlag.Enabled {
+			pd.SetLoop(loop)
+		}
+
+		if err = synth.RenderWAV(pd, settings.SampleRate, file, encoding); err != nil {
+			fmt.Println("Failed to render: " + err.Error())
+		}
+	} else if os.Args[1] == "tune" {
+		if len(args) > 1 {
+			printHelp()
+			return
+		}
+
+		if len(args) == 1 {
+			err = tuneFile(args[0], playback.Tuning)
+		} else {
+			err = tuneInput(settings, playback.Tuning)
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
 	} else {
 		printHelp()
 	}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"github.com/crockeo/go-tuner/filestore"
+	"github.com/crockeo/go-tuner/filestore/wav"
+	"github.com/crockeo/go-tuner/synth"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	// The set of options that can be passed to any mode.
+	flags = flag.NewFlagSet("go-tuner", flag.ContinueOnError)
+
+	// Where to send synthesized audio: portaudio, null, stdout or file:<path>.
+	sinkFlag = flags.String("sink", "portaudio", "where to send audio: portaudio, null, stdout or file:<wav/path>")
+
+	// The settings to run the synth with.
+	rateFlag   = flags.Int("rate", synth.DefaultSampleRate, "the sample rate, such as 44100, 48000 or 96000")
+	bufferFlag = flags.Int("buffer", 0, "the frames per audio buffer, or 0 to let the sink decide")
+	gainFlag   = flags.Float64("gain", float64(synth.DefaultGain), "the master gain, as a multiplier on the mixed notes")
+
+	// How many notes can play at once, and which one makes room for a new
+	// note once there are too many.
+	voicesFlag = flags.Int("voices", 0, "the most notes to play at once, or 0 for no limit")
+	stealFlag  = flags.String("steal", "oldest", "which note to cut off when over --voices: oldest or quietest")
+
+	// An extra instrument file or directory to load.
+	instrumentsFlag = flags.String("instruments", "", "a JSON instrument file, or directory of them, to load")
+
+	// The changes made to a song as it's played.
+	tempoFlag     = flags.Float64("tempo", 1, "the speed to play songs at, such as 0.7 for 70%")
+	transposeFlag = flags.Int("transpose", 0, "the number of semitones to transpose songs by")
+	centsFlag     = flags.Float64("cents", 0, "the number of cents to transpose songs by, on top of --transpose")
+
+	// The tuning to play songs in.
+	tuningFlag    = flags.String("tuning", "equal", "the tuning to play in: equal, just, pythagorean, meantone or a Scala <scl/path>")
+	referenceFlag = flags.Float64("reference", float64(synth.A4), "the frequency of A4, such as 440, 442 or 415")
+	tonicFlag     = flags.String("tonic", "C", "the note that tunings other than equal are built up from")
+	kbmFlag       = flags.String("kbm", "", "a Scala keyboard mapping to use in place of --reference and --tonic")
+
+	// The region of the song to loop, if any.
+	loopFlag = new(loopValue)
+)
+
+// Registering the options that need their own flag.Value.
+func init() {
+	flags.Var(loopFlag, "loop", "loop the whole song forever, or loop <start>:<end>[:<count>] in seconds, where an empty end is the end of the song")
+}
+
+// Type loopValue is a flag.Value for the --loop option. It can be given on its
+// own like a boolean option to loop the whole song forever.
+type loopValue struct {
+	Enabled bool
+	Region  synth.LoopRegion
+}
+
+// Getting the flag as a string.
+func (lv *loopValue) String() string {
+	if lv == nil || !lv.Enabled {
+		return ""
+	}
+
+	str := strconv.FormatFloat(float64(lv.Region.Start), 'g', -1, 32) + ":"
+	if lv.Region.End != 0 {
+		str += strconv.FormatFloat(float64(lv.Region.End), 'g', -1, 32)
+	}
+
+	if lv.Region.Count != synth.LoopForever {
+		str += ":" + strconv.Itoa(lv.Region.Count)
+	}
+
+	return str
+}
+
+// Letting the flag package accept --loop without a value.
+func (lv *loopValue) IsBoolFlag() bool { return true }
+
+// Parsing the flag from a string.
+func (lv *loopValue) Set(str string) error {
+	lv.Enabled = true
+	lv.Region = synth.LoopRegion{}
+
+	switch str {
+	case "true":
+		return nil
+	case "false":
+		lv.Enabled = false
+		return nil
+	}
+
+	parts := strings.Split(str, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return errors.New("Loops are given as <start>:<end>[:<count>].")
+	}
+
+	if parts[0] != "" {
+		start, err := strconv.ParseFloat(parts[0], 32)
+		if err != nil {
+			return err
+		}
+		lv.Region.Start = float32(start)
+	}
+
+	if parts[1] != "" {
+		end, err := strconv.ParseFloat(parts[1], 32)
+		if err != nil {
+			return err
+		}
+		lv.Region.End = float32(end)
+	}
+
+	if len(parts) == 3 {
+		count, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return err
+		}
+		lv.Region.Count = count
+	}
+
+	return lv.Region.Validate()
+}
+
+// The directory of instrument files loaded at startup, when it exists.
+const defaultInstruments string = "res/instruments"
+
+// Parsing the arguments after the mode, allowing options to be placed anywhere
+// between the positional arguments. Returns the positional arguments.
+func parseArgs(args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// Constructing the synth.Settings described by the options.
+func parseSettings() (synth.Settings, error) {
+	settings := synth.DefaultSettings()
+	settings.SampleRate = *rateFlag
+	settings.BufferSize = *bufferFlag
+	settings.MaxVoices = *voicesFlag
+	settings.Gain = float32(*gainFlag)
+
+	policy, err := synth.ParseStealPolicy(*stealFlag)
+	if err != nil {
+		return settings, err
+	}
+	settings.Policy = policy
+
+	return settings, settings.Validate()
+}
+
+// Constructing the synth.PlaybackOptions described by the options.
+func parsePlayback() (synth.PlaybackOptions, error) {
+	options := synth.DefaultPlaybackOptions()
+	options.Tempo = float32(*tempoFlag)
+	options.Semitones = *transposeFlag
+	options.Cents = float32(*centsFlag)
+
+	tuning, err := parseTuning()
+	if err != nil {
+		return options, err
+	}
+	options.Tuning = tuning
+
+	return options, options.Validate()
+}
+
+// Constructing the synth.Tuning described by the options.
+func parseTuning() (synth.Tuning, error) {
+	var scale synth.Scale
+	switch *tuningFlag {
+	case "equal":
+		scale = synth.EqualScale(12)
+	case "just":
+		scale = synth.JustScale
+	case "pythagorean":
+		scale = synth.PythagoreanScale
+	case "meantone":
+		scale = synth.MeantoneScale(synth.QuarterCommaFifth)
+	default:
+		if filepath.Ext(*tuningFlag) != ".scl" {
+			return nil, errors.New("Unknown tuning: " + *tuningFlag)
+		}
+
+		var err error
+		if scale, err = filestore.LoadScale(*tuningFlag); err != nil {
+			return nil, err
+		}
+	}
+
+	if *kbmFlag != "" {
+		mapping, err := filestore.LoadKeyboardMapping(*kbmFlag)
+		if err != nil {
+			return nil, err
+		}
+
+		return synth.NewScaleTuning(scale, mapping)
+	}
+
+	// The tonic is given without an octave, since only its place in the octave
+	// matters.
+	tonic, err := synth.NoteToInt(*tonicFlag + "4")
+	if err != nil {
+		return nil, errors.New("Invalid tonic: " + *tonicFlag)
+	}
+
+	return synth.NewScaleTuning(scale, synth.StandardMapping(tonic, float32(*referenceFlag)))
+}
+
+// Constructing the synth.Sink described by the --sink option.
+func parseSink(name string) (synth.Sink, error) {
+	switch {
+	case name == "portaudio":
+		return synth.PortAudioSink{}, nil
+	case name == "null":
+		return synth.NullSink{}, nil
+	case name == "stdout":
+		return synth.StdoutSink{}, nil
+	case strings.HasPrefix(name, "file:") && len(name) > len("file:"):
+		return synth.FileSink{Path: name[len("file:"):], Encoding: wav.PCM16}, nil
+	default:
+		return nil, errors.New("Unknown sink: " + name)
+	}
+}
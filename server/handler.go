@@ -3,9 +3,12 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/crockeo/go-tuner/config"
 	"github.com/crockeo/go-tuner/synth"
+	"strconv"
+	"strings"
 )
 
 //// Attempting to parse a given message into a DelayedNote. It will return
@@ -45,3 +48,45 @@ func HandleMessage(str string, noteChannel chan synth.DelayedNoteData) error {
 
 	return nil
 }
+
+// Attempting to handle a transport command, controlling playback through a
+// synth.Controller. It returns whether the message was a command, along with a
+// reply to send back when the command has one.
+//
+// pause          - Pausing playback.
+// resume         - Resuming playback.
+// seek <seconds> - Moving playback to a given time.
+// position       - Replying with the current time of playback.
+// stop           - Stopping the synth.
+func HandleCommand(str string, controller *synth.Controller) (string, bool, error) {
+	fields := strings.Fields(str)
+	if len(fields) == 0 {
+		return "", false, nil
+	}
+
+	switch fields[0] {
+	case "pause":
+		controller.Pause()
+	case "resume":
+		controller.Resume()
+	case "seek":
+		if len(fields) != 2 {
+			return "", true, errors.New("Seek takes a time in seconds.")
+		}
+
+		seconds, err := strconv.ParseFloat(fields[1], 32)
+		if err != nil {
+			return "", true, err
+		}
+
+		controller.Seek(float32(seconds))
+	case "position":
+		return strconv.FormatFloat(float64(controller.Position()), 'f', 3, 32), true, nil
+	case "stop":
+		controller.Stop()
+	default:
+		return "", false, nil
+	}
+
+	return "", true, nil
+}
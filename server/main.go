@@ -10,7 +10,7 @@ import (
 )
 
 // Handling a particular TCP connection.
-func handleTCPConnection(conn *net.TCPConn, noteChannel chan synth.DelayedNoteData) {
+func handleTCPConnection(conn *net.TCPConn, noteChannel chan synth.DelayedNoteData, controller *synth.Controller) {
 	defer conn.Close()
 	buffer := make([]byte, 256)
 	for {
@@ -25,6 +25,21 @@ func handleTCPConnection(conn *net.TCPConn, noteChannel chan synth.DelayedNoteDa
 		if rlen > 0 {
 			strs := strings.Split(strings.TrimSpace(string(buffer[:rlen])), "\n")
 			for _, v := range strs {
+				if controller != nil {
+					reply, ok, err := HandleCommand(v, controller)
+					if err != nil {
+						fmt.Println("Failed to handle command \"" + v + "\": " + err.Error())
+					}
+
+					if reply != "" {
+						conn.Write([]byte(reply + "\n"))
+					}
+
+					if ok {
+						continue
+					}
+				}
+
 				err = HandleMessage(v, noteChannel)
 				if err != nil {
 					fmt.Println("Failed to handle message \"" + v + "\": " + err.Error())
@@ -37,8 +52,9 @@ func handleTCPConnection(conn *net.TCPConn, noteChannel chan synth.DelayedNoteDa
 	}
 }
 
-// Starting the go-tuner server either on the main thread or another thread.
-func Start(errChannel chan error, noteChannel chan synth.DelayedNoteData) {
+// Starting the go-tuner server either on the main thread or another thread. If
+// controller isn't nil, connections can also send transport commands to it.
+func Start(errChannel chan error, noteChannel chan synth.DelayedNoteData, controller *synth.Controller) {
 	addr := net.TCPAddr{
 		Port: 3000,
 		IP:   net.ParseIP("127.0.0.1"),
@@ -59,7 +75,7 @@ func Start(errChannel chan error, noteChannel chan synth.DelayedNoteData) {
 			continue
 		}
 
-		go handleTCPConnection(conn, noteChannel)
+		go handleTCPConnection(conn, noteChannel, controller)
 
 		time.Sleep(1 * time.Millisecond)
 	}
@@ -0,0 +1,139 @@
+package synth
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const (
+	DefaultGain      float32 = 1.0 // The master gain a MasterBus starts with. Soft clipping keeps louder mixes in range.
+	DefaultThreshold float32 = 0.6 // The level a MasterBus starts soft clipping at.
+)
+
+// The number of frames the meter on a MasterBus measures over.
+const meterWindow int = 2048
+
+// Type MasterBus is the final stage that the mixed voices of a PrimaryDriver go
+// through: a master gain, a soft clipping limiter that keeps the output inside
+// of [-1, 1], and a peak and RMS meter.
+//
+// The gain, threshold and meter are stored atomically so that they can be
+// changed and queried while audio is being produced.
+type MasterBus struct {
+	gain      uint32 // The bits of the float32 master gain.
+	threshold uint32 // The bits of the float32 soft clipping threshold.
+
+	peak uint32 // The bits of the float32 peak level of the last window.
+	rms  uint32 // The bits of the float32 RMS level of the last window.
+
+	windowPeak    float32 // The peak level of the current window.
+	windowSquares float64 // The sum of squares of the current window.
+	windowSamples int     // The number of samples in the current window.
+	windowFrames  int     // The number of frames in the current window.
+}
+
+// Creating a MasterBus with the default gain and threshold.
+func NewMasterBus() *MasterBus {
+	mb := new(MasterBus)
+
+	mb.SetGain(DefaultGain)
+	mb.SetThreshold(DefaultThreshold)
+
+	return mb
+}
+
+// Getting the master gain.
+func (mb *MasterBus) Gain() float32 {
+	return math.Float32frombits(atomic.LoadUint32(&mb.gain))
+}
+
+// Setting the master gain, as a multiplier on the mixed voices.
+func (mb *MasterBus) SetGain(gain float32) {
+	atomic.StoreUint32(&mb.gain, math.Float32bits(gain))
+}
+
+// Getting the level that soft clipping starts at.
+func (mb *MasterBus) Threshold() float32 {
+	return math.Float32frombits(atomic.LoadUint32(&mb.threshold))
+}
+
+// Setting the level that soft clipping starts at, from 0 to 1. Anything below
+// it passes through untouched, and anything above it is curved smoothly
+// towards 1.
+func (mb *MasterBus) SetThreshold(threshold float32) {
+	if threshold < 0 {
+		threshold = 0
+	} else if threshold > 1 {
+		threshold = 1
+	}
+
+	atomic.StoreUint32(&mb.threshold, math.Float32bits(threshold))
+}
+
+// Getting the peak output level over the most recent meter window.
+func (mb *MasterBus) Peak() float32 {
+	return math.Float32frombits(atomic.LoadUint32(&mb.peak))
+}
+
+// Getting the RMS output level over the most recent meter window.
+func (mb *MasterBus) RMS() float32 {
+	return math.Float32frombits(atomic.LoadUint32(&mb.rms))
+}
+
+// Converting a linear level, such as from Peak or RMS, into decibels.
+func Decibels(level float32) float32 {
+	return float32(20 * math.Log10(float64(level)))
+}
+
+// Soft clipping a single sample so that it never goes past 1.
+func softClip(sample, threshold float32) float32 {
+	magnitude := float32(math.Abs(float64(sample)))
+	if magnitude <= threshold {
+		return sample
+	}
+
+	// Above the threshold the sample follows a tanh curve, which meets the
+	// straight line below it with the same slope.
+	knee := 1 - threshold
+	clipped := threshold
+	if knee > 0 {
+		clipped += knee * float32(math.Tanh(float64((magnitude-threshold)/knee)))
+	}
+
+	if sample < 0 {
+		return -clipped
+	}
+
+	return clipped
+}
+
+// Passing a frame of mixed output through the bus, modifying it in place.
+func (mb *MasterBus) Process(frame []float32) []float32 {
+	gain := mb.Gain()
+	threshold := mb.Threshold()
+
+	for i, sample := range frame {
+		sample = softClip(sample*gain, threshold)
+		frame[i] = sample
+
+		if magnitude := float32(math.Abs(float64(sample))); magnitude > mb.windowPeak {
+			mb.windowPeak = magnitude
+		}
+
+		mb.windowSquares += float64(sample) * float64(sample)
+		mb.windowSamples++
+	}
+
+	mb.windowFrames++
+	if mb.windowFrames >= meterWindow {
+		atomic.StoreUint32(&mb.peak, math.Float32bits(mb.windowPeak))
+		atomic.StoreUint32(&mb.rms, math.Float32bits(float32(math.Sqrt(mb.windowSquares/float64(mb.windowSamples)))))
+
+		mb.windowPeak = 0
+		mb.windowSquares = 0
+		mb.windowSamples = 0
+		mb.windowFrames = 0
+	}
+
+	return frame
+}
@@ -2,9 +2,10 @@ package synth
 
 import (
 	"fmt"
-	"github.com/HardWareGuy/portaudio-go"
 	"github.com/crockeo/go-tuner/config"
 	"math"
+	"sort"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,15 +30,18 @@ type Driver interface {
 
 // The driver to play a single note for a given duration.
 type SingleDriver struct {
-	Note      NoteData  // The data for this note.
-	Phases    []float32 // The current phase of the driver.
-	Time      float32   // The current time of the SingleDriver.
-	StartTime float32   // The time this driver was started - if used without a PrimaryDriver it will always be 0.
+	Note    NoteData  // The data for this note.
+	Phases  []float32 // The current phase of the driver.
+	Start   int64     // The sample this driver was started on - if used without a PrimaryDriver it will always be 0.
+	Samples int64     // The number of samples this driver has played.
+	Time    float32   // The time in seconds this driver has played for, worked out from Samples.
+
+	gains [2]float32 // The left and right gains from the note's pan.
 }
 
 // Creating a new SingleDriver to be used as a player inside of a PrimaryDriver
-// from a given NoteData.
-func NewSingleDriverChild(nd NoteData, startTime float32) *SingleDriver {
+// from a given NoteData and the sample it starts on.
+func NewSingleDriverChild(nd NoteData, start int64) *SingleDriver {
 	sd := new(SingleDriver)
 
 	sd.Note = nd
@@ -47,8 +51,14 @@ func NewSingleDriverChild(nd NoteData, startTime float32) *SingleDriver {
 		sd.Phases[i] = 0
 	}
 
-	sd.Time = startTime
-	sd.StartTime = startTime
+	sd.Start = start
+	sd.Samples = 0
+	sd.Time = 0
+
+	// Using a constant power pan law, so a note keeps the same loudness as it
+	// moves across the stereo field.
+	angle := float64(nd.Pan+1) * math.Pi / 4
+	sd.gains = [2]float32{float32(math.Cos(angle)), float32(math.Sin(angle))}
 
 	return sd
 }
@@ -60,7 +70,7 @@ func NewSingleDriver(nd NoteData) *SingleDriver {
 
 // Calculating the time in seconds that this driver should be running.
 func (sd *SingleDriver) CalculateDuration() time.Duration {
-	return time.Duration(sd.Note.Duration)
+	return time.Duration(sd.Note.Duration + sd.Note.Envelope.Tail())
 }
 
 // Getting the number of output channels this driver is expecting.
@@ -70,6 +80,8 @@ func (sd *SingleDriver) OutputChannels() int {
 
 // Calculating the output on whatever set of channels for a given driver.
 func (sd *SingleDriver) CalculateOutput() []float32 {
+	level := sd.Note.Envelope.Level(sd.Time, sd.Note.Duration)
+
 	var sum float32 = 0
 	for i, phase := range sd.Phases {
 		var vol float32
@@ -79,20 +91,21 @@ func (sd *SingleDriver) CalculateOutput() []float32 {
 			vol = sd.Note.Volume * sd.Note.Overtones[i-1].Volume
 		}
 
-		sum += float32(math.Sin(float64(phase))) * vol * sd.Note.FadeFunc(sd.Time-sd.StartTime, sd.Note.Duration)
+		sum += float32(math.Sin(float64(phase))) * vol * level
 	}
 
 	outputs := make([]float32, sd.OutputChannels())
 	for k, _ := range outputs {
-		outputs[k] = sum
+		outputs[k] = sum * sd.gains[k]
 	}
 
 	return outputs
 }
 
-// Finding out if a driver is finished playing.
+// Finding out if a driver is finished playing, including the release tail of
+// its envelope.
 func (sd *SingleDriver) Finished() bool {
-	return sd.Time-sd.StartTime > sd.Note.Duration
+	return sd.Time > sd.Note.Duration+sd.Note.Envelope.Tail()
 }
 
 // Stepping the internal phases given a sample rate.
@@ -111,16 +124,67 @@ func (sd *SingleDriver) StepPhases(sampleRate int) {
 		}
 	}
 
-	sd.Time += 1.0 / float32(sampleRate)
+	// Working the time out from a whole number of samples, rather than adding
+	// up fractions of a second, keeps it from drifting over long notes.
+	sd.Samples++
+	sd.Time = float32(float64(sd.Samples) / float64(sampleRate))
+}
+
+// The number of notes that can be waiting in a PrimaryDriver's inbox before
+// sending to it blocks.
+const inboxSize int = 256
+
+// The number of transport commands that can be waiting for a PrimaryDriver
+// before sending another blocks.
+const commandsSize int = 16
+
+// Type ScheduledNote is a note along with the absolute sample that it starts
+// on.
+type ScheduledNote struct {
+	Start int64
+	ND    NoteData
 }
 
 // The primary driver that is used by the rest of the program by default to
 // start whichever synth.
+//
+// Notes are played at absolute sample indices. Notes added with a relative
+// delay are queued until the sample rate is known, and then scheduled relative
+// to the start of the note before them - or to the current sample, when
+// nothing is waiting to be played.
+//
+// A PrimaryDriver belongs to whichever goroutine is stepping it - the audio
+// callback, once it has been handed to a Sink. Notes that arrive from anywhere
+// else have to be sent through Inbox, which is drained from StepPhases, and
+// playback is controlled from elsewhere through a Controller.
 type PrimaryDriver struct {
-	QueuedNotes  []DelayedNoteData // The list of NoteDatas to add.
-	CurrentNotes []*SingleDriver   // The list of current SingleDrivers.
-	Time         float32           // The current time of the PrimaryDriver.
-	LastTime     float32           // The time that the last SingleDriver was added.
+	// The sample position, published for a Controller to read atomically. It's
+	// kept first so that it's 64-bit aligned on 32-bit platforms.
+	position int64
+
+	QueuedNotes    []DelayedNoteData // The list of NoteDatas to schedule.
+	ScheduledNotes []ScheduledNote   // The scheduled notes in order of their start, including played notes when KeepHistory is set.
+	KeepHistory    bool              // Whether notes are kept after playing, so that the driver can seek back to them.
+	CurrentNotes   []*SingleDriver   // The list of current SingleDrivers.
+	Sample         int64             // The index of the next sample to be produced.
+	SampleRate     int               // The sample rate the driver was last stepped at.
+	Time           float32           // The current time of the PrimaryDriver, worked out from Sample.
+	MaxVoices      int               // The most SingleDrivers to play at once, or 0 for no limit.
+	Policy         StealPolicy       // How to pick a SingleDriver to cut off when over MaxVoices.
+	Bus            *MasterBus        // The gain, limiter and meter applied to the mixed output.
+	Paused         bool              // Whether playback is paused.
+
+	next      int                       // The index of the next note in ScheduledNotes to start.
+	forgotten int64                     // The latest sample any note that's been forgotten ends on.
+	lastStart float64                   // The start in seconds of the last note scheduled from a delay.
+	inbox     chan DelayedNoteData      // Notes sent from other goroutines, waiting to be queued.
+	commands  chan func(*PrimaryDriver) // Transport commands sent from a Controller.
+
+	loop      *LoopRegion // The region being looped, if there is one.
+	loopPlays int         // The number of times the loop region has started.
+	loopStart int64       // The sample the loop region starts on.
+	loopEnd   int64       // The sample the loop region ends on.
+	loopDirty bool        // Whether loopStart and loopEnd need working out again.
 }
 
 // Creating a PrimaryDriver from existent data.
@@ -128,21 +192,33 @@ func NewPrimaryDriver(queuedNotes []DelayedNoteData) *PrimaryDriver {
 	pd := new(PrimaryDriver)
 
 	pd.QueuedNotes = queuedNotes
+	pd.ScheduledNotes = []ScheduledNote{}
+	pd.KeepHistory = true
 	pd.CurrentNotes = []*SingleDriver{}
+	pd.Sample = 0
+	pd.SampleRate = DefaultSampleRate
 	pd.Time = 0.0
-	pd.LastTime = 0.0
+	pd.Bus = NewMasterBus()
+	pd.inbox = make(chan DelayedNoteData, inboxSize)
+	pd.commands = make(chan func(*PrimaryDriver), commandsSize)
 
 	return pd
 }
 
-// Creating a PrimaryDriver with no information inside yet.
+// Creating a PrimaryDriver with no information inside yet. Notes are expected
+// to be fed to it for as long as it runs, so it doesn't keep its history.
 func NewPrimaryDriverEmpty() *PrimaryDriver {
 	pd := new(PrimaryDriver)
 
 	pd.QueuedNotes = []DelayedNoteData{}
+	pd.ScheduledNotes = []ScheduledNote{}
 	pd.CurrentNotes = []*SingleDriver{}
+	pd.Sample = 0
+	pd.SampleRate = DefaultSampleRate
 	pd.Time = 0.0
-	pd.LastTime = 0.0
+	pd.Bus = NewMasterBus()
+	pd.inbox = make(chan DelayedNoteData, inboxSize)
+	pd.commands = make(chan func(*PrimaryDriver), commandsSize)
 
 	return pd
 }
@@ -151,12 +227,20 @@ func NewPrimaryDriverEmpty() *PrimaryDriver {
 func (pd *PrimaryDriver) CalculateDuration() time.Duration {
 	var delay, max float32
 
-	delay = 0
-	max = 0
+	delay = float32(pd.lastStart)
+	max = float32(float64(pd.forgotten) / float64(pd.SampleRate))
+
+	for _, sn := range pd.ScheduledNotes {
+		v := float32(float64(sn.Start)/float64(pd.SampleRate)) + sn.ND.Duration + sn.ND.Envelope.Tail()
+
+		if v > max {
+			max = v
+		}
+	}
 
 	for _, qn := range pd.QueuedNotes {
 		delay += qn.Delay
-		v := delay + qn.ND.Duration
+		v := delay + qn.ND.Duration + qn.ND.Envelope.Tail()
 
 		if v > max {
 			max = v
@@ -166,19 +250,109 @@ func (pd *PrimaryDriver) CalculateDuration() time.Duration {
 	return time.Duration(max)
 }
 
+// Getting the channel to send notes through while the PrimaryDriver is being
+// run. It's the only way to add notes that's safe from another goroutine.
+func (pd *PrimaryDriver) Inbox() chan<- DelayedNoteData {
+	return pd.inbox
+}
+
+// Moving every note waiting in the inbox into the list of queued notes. This
+// never blocks, so it's safe to call from inside of the audio callback.
+func (pd *PrimaryDriver) drainInbox() {
+	for {
+		select {
+		case dnd := <-pd.inbox:
+			pd.AddDelayedNote(dnd)
+		default:
+			return
+		}
+	}
+}
+
 // Trying to add a new DelayedNoteData to the list of queued notes inside of a
-// PrimaryDriver.
+// PrimaryDriver. This must only be called from the goroutine that steps the
+// driver - use Inbox otherwise.
 func (pd *PrimaryDriver) AddDelayedNote(dnd DelayedNoteData) {
 	if config.DebugMode {
 		fmt.Print("Adding note: ")
 		fmt.Println(dnd.ND)
 	}
 
-	if len(pd.QueuedNotes) == 0 {
-		pd.LastTime = pd.Time
+	pd.QueuedNotes = append(pd.QueuedNotes, dnd)
+}
+
+// Adding a note that starts on an absolute sample index. Notes scheduled in
+// the past start on the next sample.
+func (pd *PrimaryDriver) AddScheduledNote(sn ScheduledNote) {
+	if config.DebugMode {
+		fmt.Print("Scheduling note: ")
+		fmt.Println(sn)
 	}
 
-	pd.QueuedNotes = append(pd.QueuedNotes, dnd)
+	if sn.Start < pd.Sample {
+		sn.Start = pd.Sample
+	}
+	pd.loopDirty = true
+
+	// Finding the first note that starts after this one, so that notes with
+	// the same start stay in the order they were added.
+	i := sort.Search(len(pd.ScheduledNotes), func(i int) bool {
+		return pd.ScheduledNotes[i].Start > sn.Start
+	})
+
+	pd.ScheduledNotes = append(pd.ScheduledNotes, ScheduledNote{})
+	copy(pd.ScheduledNotes[i+1:], pd.ScheduledNotes[i:])
+	pd.ScheduledNotes[i] = sn
+}
+
+// Forgetting the notes that have started, so that a driver fed notes forever
+// doesn't grow forever. Notes in a loop region are kept until the loop is
+// over, since they'll be played again.
+func (pd *PrimaryDriver) forgetPlayed() {
+	played := pd.next
+	if pd.looping() {
+		played = sort.Search(pd.next, func(i int) bool {
+			return pd.ScheduledNotes[i].Start >= pd.loopStart
+		})
+	}
+
+	if played == 0 {
+		return
+	}
+
+	// Remembering where the forgotten notes end, which a loop without an end
+	// still reaches to.
+	rate := float64(pd.SampleRate)
+	for _, sn := range pd.ScheduledNotes[:played] {
+		if end := sn.Start + int64(math.Floor(float64(sn.ND.Duration)*rate+0.5)); end > pd.forgotten {
+			pd.forgotten = end
+		}
+	}
+
+	pd.ScheduledNotes = pd.ScheduledNotes[played:]
+	pd.next -= played
+}
+
+// Scheduling every queued note, turning its relative delay into an absolute
+// sample index.
+func (pd *PrimaryDriver) scheduleQueued() {
+	now := float64(pd.Sample) / float64(pd.SampleRate)
+
+	for _, dnd := range pd.QueuedNotes {
+		// With nothing waiting to be played, a delay counts from now rather
+		// than from a note that's already started.
+		if pd.next == len(pd.ScheduledNotes) && pd.lastStart < now {
+			pd.lastStart = now
+		}
+
+		pd.lastStart += float64(dnd.Delay)
+		pd.AddScheduledNote(ScheduledNote{
+			int64(math.Floor(pd.lastStart*float64(pd.SampleRate) + 0.5)),
+			dnd.ND,
+		})
+	}
+
+	pd.QueuedNotes = pd.QueuedNotes[:0]
 }
 
 // Getting the number of output channels this driver is expecting.
@@ -187,18 +361,22 @@ func (pd *PrimaryDriver) OutputChannels() int { return 2 }
 // Calculating the output on whatever set of channels for a given driver.
 func (pd *PrimaryDriver) CalculateOutput() []float32 {
 	vs := []float32{0.0, 0.0}
+	if pd.Paused {
+		return vs
+	}
+
 	for _, sd := range pd.CurrentNotes {
 		for i, o := range sd.CalculateOutput() {
 			vs[i] += o
 		}
 	}
 
-	return vs
+	return pd.Bus.Process(vs)
 }
 
 // Finding out if a driver is finished playing.
 func (pd *PrimaryDriver) Finished() bool {
-	if len(pd.QueuedNotes) > 0 {
+	if len(pd.QueuedNotes) > 0 || pd.next < len(pd.ScheduledNotes) || pd.looping() {
 		return false
 	}
 
@@ -213,35 +391,41 @@ func (pd *PrimaryDriver) Finished() bool {
 
 // Stepping the internal phases given a sample rate.
 func (pd *PrimaryDriver) StepPhases(sampleRate int) {
-	// Appending new notes to the set of current notes.
-	for len(pd.QueuedNotes) > 0 && pd.Time-pd.LastTime >= pd.QueuedNotes[0].Delay {
-		if config.DebugMode {
-			fmt.Print("Playing note: ")
-			fmt.Println(pd.QueuedNotes[0].ND)
-		}
+	if sampleRate != pd.SampleRate {
+		pd.SampleRate = sampleRate
+		pd.loopDirty = true
+	}
+
+	pd.drainInbox()
 
-		pd.CurrentNotes = append(pd.CurrentNotes, NewSingleDriverChild(pd.QueuedNotes[0].ND, pd.Time))
-		pd.QueuedNotes = pd.QueuedNotes[1:]
+	// Dropping any voice that has finished, so that a long note doesn't keep
+	// the notes after it alive.
+	pd.dropFinished()
 
-		pd.LastTime = pd.Time
+	if len(pd.QueuedNotes) > 0 {
+		pd.scheduleQueued()
 	}
 
-	// Finding the last index of a note that should be deleted.
-	var sieve int
-	for sieve = -1; sieve < len(pd.CurrentNotes)-1; sieve++ {
-		n := pd.CurrentNotes[sieve+1]
-		if pd.Time < n.StartTime+n.Note.Duration {
-			break
-		}
+	// Running transport commands after scheduling, so that a seek can reach
+	// notes that have only just been added.
+	pd.drainCommands()
+	if pd.Paused {
+		return
 	}
 
-	// Deleting everything before the sieve.
-	if sieve != -1 {
+	// Appending new notes to the set of current notes.
+	for pd.next < len(pd.ScheduledNotes) && pd.ScheduledNotes[pd.next].Start <= pd.Sample {
 		if config.DebugMode {
-			fmt.Printf("Removing to sieve: %d\n", sieve)
+			fmt.Print("Playing note: ")
+			fmt.Println(pd.ScheduledNotes[pd.next].ND)
 		}
 
-		pd.CurrentNotes = pd.CurrentNotes[sieve+1:]
+		pd.startVoice(NewSingleDriverChild(pd.ScheduledNotes[pd.next].ND, pd.Sample))
+		pd.next++
+	}
+
+	if !pd.KeepHistory {
+		pd.forgetPlayed()
 	}
 
 	// Stepping the phases for the sub drivers.
@@ -249,7 +433,11 @@ func (pd *PrimaryDriver) StepPhases(sampleRate int) {
 		sd.StepPhases(sampleRate)
 	}
 
-	pd.Time += 1.0 / float32(sampleRate)
+	pd.Sample++
+	pd.wrapLoop()
+
+	pd.Time = float32(float64(pd.Sample) / float64(sampleRate))
+	atomic.StoreInt64(&pd.position, pd.Sample)
 }
 
 // Returning a function to drive music synthesis given a driver and a sample
@@ -271,25 +459,3 @@ func DriverFunction(driver Driver, sampleRate int, quitWhenDone bool, exitChanne
 		}
 	}
 }
-
-// Running a synth server.
-func RunSynth(driver Driver, errChannel chan error, quitWhenDone bool, exitChannel chan bool) {
-	err := portaudio.Initialize()
-	if err != nil {
-		errChannel <- err
-		<-exitChannel
-	}
-	defer portaudio.Terminate()
-
-	stream, err := portaudio.OpenDefaultStream(0, 2, 44100, 0, DriverFunction(driver, 44100, quitWhenDone, exitChannel))
-	if err != nil {
-		errChannel <- err
-		<-exitChannel
-	}
-	defer stream.Close()
-
-	stream.Start()
-	defer stream.Stop()
-
-	<-exitChannel
-}
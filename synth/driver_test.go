@@ -0,0 +1,66 @@
+package synth
+
+import (
+	"testing"
+)
+
+func TestLiveDriverForgetsPlayedNotes(t *testing.T) {
+	pd := NewPrimaryDriverEmpty()
+	nd := NoteData{Duration: 0.01, Volume: 1, Frequency: 440, Envelope: Envelope{Sustain: 1}}
+
+	// Feeding a note in every 10 samples, like a server that never stops
+	// getting notes.
+	for i := 0; i < 100000; i++ {
+		if i%10 == 0 {
+			pd.AddDelayedNote(DelayedNoteData{Delay: 0, ND: nd})
+		}
+
+		pd.CalculateOutput()
+		pd.StepPhases(loopTestRate)
+	}
+
+	if len(pd.ScheduledNotes) > 1 {
+		t.Errorf("Expected played notes to be forgotten, but %d are still scheduled.", len(pd.ScheduledNotes))
+	}
+}
+
+func TestArrangementDriverKeepsHistory(t *testing.T) {
+	nd := NoteData{Duration: 0.5, Volume: 1, Frequency: 440, Envelope: Envelope{Sustain: 1}}
+	pd := NewPrimaryDriver(NoteArrangement{{Delay: 0, ND: nd}, {Delay: 1, ND: nd}})
+
+	for i := 0; i < 2*loopTestRate; i++ {
+		pd.CalculateOutput()
+		pd.StepPhases(loopTestRate)
+	}
+
+	// Seeking back to the first note has to find it again.
+	pd.Seek(0.25)
+	if len(pd.CurrentNotes) != 1 || pd.CurrentNotes[0].Start != 0 {
+		t.Errorf("Expected seeking back to restart the first note, got %d voices.", len(pd.CurrentNotes))
+	}
+}
+
+func TestLiveDriverLoopsForgottenNotes(t *testing.T) {
+	pd := loopTestDriver(4)
+	if err := pd.SetLoop(LoopRegion{Start: 2, Count: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The notes before the region are forgotten as soon as they start, but the
+	// region still runs to the end of the last note.
+	starts, _ := playLoop(pd, 100*loopTestRate)
+	want := []int64{1000, 2000, 3000, 4000, 2000, 3000, 4000}
+	if len(starts) != len(want) {
+		t.Fatalf("Expected starts %v, got %v.", want, starts)
+	}
+
+	for i := range want {
+		if starts[i] != want[i] {
+			t.Fatalf("Expected starts %v, got %v.", want, starts)
+		}
+	}
+
+	if len(pd.ScheduledNotes) != 0 {
+		t.Errorf("Expected every note to be forgotten once the loop was over, but %d are still scheduled.", len(pd.ScheduledNotes))
+	}
+}
@@ -0,0 +1,125 @@
+package synth
+
+import (
+	"errors"
+	"math"
+)
+
+// Type Curve is the shape an envelope takes while moving between two levels.
+type Curve int
+
+const (
+	LinearCurve      Curve = iota // Moves at a constant rate.
+	ExponentialCurve              // Moves quickly at first, then settles.
+	SmoothCurve                   // Eases in and out of the move.
+)
+
+// The names of curves, as used in instrument files.
+var curveNames = map[Curve]string{
+	LinearCurve:      "linear",
+	ExponentialCurve: "exponential",
+	SmoothCurve:      "smooth",
+}
+
+// Getting how far along a curve has moved, from 0 to 1, when a given fraction
+// of its time has passed.
+func (c Curve) Apply(x float32) float32 {
+	if x <= 0 {
+		return 0
+	} else if x >= 1 {
+		return 1
+	}
+
+	switch c {
+	case ExponentialCurve:
+		return float32((1 - math.Exp(-5*float64(x))) / (1 - math.Exp(-5)))
+	case SmoothCurve:
+		return x * x * (3 - 2*x)
+	default:
+		return x
+	}
+}
+
+// Writing a curve out as its name.
+func (c Curve) MarshalText() ([]byte, error) {
+	name, ok := curveNames[c]
+	if !ok {
+		return nil, errors.New("Invalid curve.")
+	}
+
+	return []byte(name), nil
+}
+
+// Reading a curve in from its name.
+func (c *Curve) UnmarshalText(text []byte) error {
+	for curve, name := range curveNames {
+		if name == string(text) {
+			*c = curve
+			return nil
+		}
+	}
+
+	return errors.New("Invalid curve name: " + string(text))
+}
+
+// Type Envelope is an attack/decay/sustain/release envelope controlling the
+// volume of a note over its life. The release begins once the note's duration
+// is up, and runs past it as the note's tail.
+type Envelope struct {
+	Attack  float32 `json:"attack"`  // Seconds to rise from silence to full volume.
+	Decay   float32 `json:"decay"`   // Seconds to fall from full volume to the sustain level.
+	Sustain float32 `json:"sustain"` // The level held until the note's duration is up, from 0 to 1.
+	Release float32 `json:"release"` // Seconds to fall to silence after the note's duration.
+
+	AttackCurve  Curve `json:"attackCurve"`
+	DecayCurve   Curve `json:"decayCurve"`
+	ReleaseCurve Curve `json:"releaseCurve"`
+}
+
+// Checking that an envelope's times and levels make sense.
+func (e Envelope) Validate() error {
+	if e.Attack < 0 || e.Decay < 0 || e.Release < 0 {
+		return errors.New("Envelope times cannot be negative.")
+	} else if e.Sustain < 0 || e.Sustain > 1 {
+		return errors.New("Envelope sustain must be between 0 and 1.")
+	}
+
+	return nil
+}
+
+// The length of time, in seconds, that a note keeps sounding after its
+// duration is up.
+func (e Envelope) Tail() float32 {
+	return e.Release
+}
+
+// The level of the envelope while a note is held down.
+func (e Envelope) held(time float32) float32 {
+	if time < e.Attack {
+		return e.AttackCurve.Apply(time / e.Attack)
+	}
+
+	time -= e.Attack
+	if time < e.Decay {
+		return 1 - (1-e.Sustain)*e.DecayCurve.Apply(time/e.Decay)
+	}
+
+	return e.Sustain
+}
+
+// Getting the level of the envelope a given time into a note with a given
+// duration.
+func (e Envelope) Level(time, duration float32) float32 {
+	if time < 0 {
+		return 0
+	} else if time < duration {
+		return e.held(time)
+	}
+
+	released := time - duration
+	if released >= e.Release {
+		return 0
+	}
+
+	return e.held(duration) * (1 - e.ReleaseCurve.Apply(released/e.Release))
+}
@@ -0,0 +1,281 @@
+package synth
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Type Instrument is a function that creates the NoteData for a note of a
+// given duration, volume and frequency.
+type Instrument func(duration, volume, frequency float32) NoteData
+
+// Guarding the instruments map, which can be added to while notes are being
+// made.
+var instrumentsLock sync.RWMutex
+
+// Registering an instrument under a given name so that it can be used in
+// arrangements. Registering a name twice replaces the first instrument.
+func RegisterInstrument(name string, instrument Instrument) error {
+	if name == "" {
+		return errors.New("Instruments must have a name.")
+	} else if instrument == nil {
+		return errors.New("Cannot register a nil instrument: " + name)
+	}
+
+	instrumentsLock.Lock()
+	defer instrumentsLock.Unlock()
+
+	instruments[name] = instrument
+	return nil
+}
+
+// Getting the instrument registered under a given name.
+func GetInstrument(name string) (Instrument, bool) {
+	instrumentsLock.RLock()
+	defer instrumentsLock.RUnlock()
+
+	instrument, ok := instruments[name]
+	return instrument, ok
+}
+
+// Getting the names of every registered instrument in sorted order.
+func InstrumentNames() []string {
+	instrumentsLock.RLock()
+	defer instrumentsLock.RUnlock()
+
+	names := make([]string, 0, len(instruments))
+	for name := range instruments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Type InstrumentDefinition describes an instrument as data rather than code,
+// so that it can be loaded from a file. Leaving out the envelope holds notes at
+// full volume with a short release.
+type InstrumentDefinition struct {
+	Name      string     `json:"name"`
+	Overtones []Overtone `json:"overtones"`
+	Envelope  *Envelope  `json:"envelope,omitempty"`
+}
+
+// Creating the Instrument described by an InstrumentDefinition.
+func (id InstrumentDefinition) Instrument() (Instrument, error) {
+	envelope := Envelope{Attack: 0.005, Sustain: 1, Release: 0.05}
+	if id.Envelope != nil {
+		envelope = *id.Envelope
+	}
+
+	if err := envelope.Validate(); err != nil {
+		return nil, errors.New(id.Name + ": " + err.Error())
+	}
+
+	overtones := make([]Overtone, len(id.Overtones))
+	copy(overtones, id.Overtones)
+
+	return func(duration, volume, frequency float32) NoteData {
+		return NoteData{
+			Duration:  duration,
+			Volume:    volume,
+			Frequency: frequency,
+			Envelope:  envelope,
+			Overtones: overtones,
+		}
+	}, nil
+}
+
+// Registering the instrument described by an InstrumentDefinition under its
+// name.
+func (id InstrumentDefinition) Register() error {
+	instrument, err := id.Instrument()
+	if err != nil {
+		return err
+	}
+
+	return RegisterInstrument(id.Name, instrument)
+}
+
+// Creating a NoteData from a piano-like note - bright harmonics that die away
+// quickly after the hammer strike.
+func PianoNote(duration, volume, frequency float32) NoteData {
+	return NoteData{
+		Duration:  duration,
+		Volume:    volume,
+		Frequency: frequency,
+
+		Envelope: Envelope{
+			Attack:       0.002,
+			Decay:        1.5,
+			Sustain:      0.15,
+			Release:      0.3,
+			DecayCurve:   ExponentialCurve,
+			ReleaseCurve: ExponentialCurve,
+		},
+
+		Overtones: []Overtone{
+			Overtone{2, 0.400},
+			Overtone{3, 0.200},
+			Overtone{4, 0.150},
+			Overtone{5, 0.080},
+			Overtone{6, 0.050},
+			Overtone{7, 0.030},
+		},
+	}
+}
+
+// Creating a NoteData from an organ note - a fixed set of drawbars held at
+// full volume.
+func OrganNote(duration, volume, frequency float32) NoteData {
+	return NoteData{
+		Duration:  duration,
+		Volume:    volume,
+		Frequency: frequency,
+
+		Envelope: Envelope{
+			Attack:  0.01,
+			Sustain: 1,
+			Release: 0.05,
+		},
+
+		Overtones: []Overtone{
+			Overtone{0.5, 0.500},
+			Overtone{1.5, 0.250},
+			Overtone{2, 0.500},
+			Overtone{3, 0.250},
+			Overtone{4, 0.300},
+			Overtone{8, 0.150},
+		},
+	}
+}
+
+// Creating a NoteData from a square wave lead - the odd harmonics, each at 1/n
+// volume.
+func SquareNote(duration, volume, frequency float32) NoteData {
+	return NoteData{
+		Duration:  duration,
+		Volume:    volume,
+		Frequency: frequency,
+
+		Envelope: Envelope{
+			Attack:  0.005,
+			Decay:   0.1,
+			Sustain: 0.8,
+			Release: 0.02,
+		},
+
+		Overtones: []Overtone{
+			Overtone{3, 0.333},
+			Overtone{5, 0.200},
+			Overtone{7, 0.142},
+			Overtone{9, 0.111},
+			Overtone{11, 0.090},
+			Overtone{13, 0.076},
+		},
+	}
+}
+
+// Creating a NoteData from a saw wave lead - every harmonic, each at 1/n
+// volume.
+func SawNote(duration, volume, frequency float32) NoteData {
+	return NoteData{
+		Duration:  duration,
+		Volume:    volume,
+		Frequency: frequency,
+
+		Envelope: Envelope{
+			Attack:  0.01,
+			Decay:   0.2,
+			Sustain: 0.7,
+			Release: 0.05,
+		},
+
+		Overtones: []Overtone{
+			Overtone{2, 0.500},
+			Overtone{3, 0.333},
+			Overtone{4, 0.250},
+			Overtone{5, 0.200},
+			Overtone{6, 0.166},
+			Overtone{7, 0.142},
+			Overtone{8, 0.125},
+			Overtone{9, 0.111},
+			Overtone{10, 0.100},
+		},
+	}
+}
+
+// Creating a NoteData from a triangle wave lead - the odd harmonics, each at
+// 1/n^2 volume with alternating signs.
+func TriangleNote(duration, volume, frequency float32) NoteData {
+	return NoteData{
+		Duration:  duration,
+		Volume:    volume,
+		Frequency: frequency,
+
+		Envelope: Envelope{
+			Attack:      0.02,
+			Sustain:     1,
+			Release:     0.08,
+			AttackCurve: SmoothCurve,
+		},
+
+		Overtones: []Overtone{
+			Overtone{3, -0.111},
+			Overtone{5, 0.040},
+			Overtone{7, -0.020},
+			Overtone{9, 0.012},
+		},
+	}
+}
+
+// Creating a NoteData from a bass note - a heavy fundamental with a few low
+// harmonics that fades slowly.
+func BassNote(duration, volume, frequency float32) NoteData {
+	return NoteData{
+		Duration:  duration,
+		Volume:    volume,
+		Frequency: frequency,
+
+		Envelope: Envelope{
+			Attack:     0.005,
+			Decay:      0.8,
+			Sustain:    0.4,
+			Release:    0.1,
+			DecayCurve: ExponentialCurve,
+		},
+
+		Overtones: []Overtone{
+			Overtone{2, 0.400},
+			Overtone{3, 0.150},
+			Overtone{4, 0.080},
+		},
+	}
+}
+
+// Creating a NoteData from a bell note - inharmonic partials that keep ringing
+// out after the note is let go.
+func BellNote(duration, volume, frequency float32) NoteData {
+	return NoteData{
+		Duration:  duration,
+		Volume:    volume,
+		Frequency: frequency,
+
+		Envelope: Envelope{
+			Attack:       0.001,
+			Decay:        4,
+			Release:      1.5,
+			DecayCurve:   ExponentialCurve,
+			ReleaseCurve: ExponentialCurve,
+		},
+
+		Overtones: []Overtone{
+			Overtone{0.56, 0.300},
+			Overtone{2.76, 0.600},
+			Overtone{5.40, 0.400},
+			Overtone{8.93, 0.250},
+			Overtone{13.34, 0.200},
+		},
+	}
+}
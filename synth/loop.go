@@ -0,0 +1,140 @@
+package synth
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// The loop count to play a LoopRegion forever.
+const LoopForever int = 0
+
+// Type LoopRegion describes a range of time in seconds to play over again. The
+// region includes its start, but not its end, so a note starting right on the
+// end of a region is never played at the loop boundary.
+type LoopRegion struct {
+	Start float32 // The time the region starts.
+	End   float32 // The time the region ends, or 0 for the end of the notes.
+	Count int     // The number of times to play the region, or LoopForever.
+}
+
+// Checking that a LoopRegion describes a range that can be played.
+func (lr LoopRegion) Validate() error {
+	if lr.Start < 0 {
+		return errors.New("Loop regions can't start before 0.")
+	}
+
+	if lr.End != 0 && lr.End <= lr.Start {
+		return errors.New("Loop regions must end after they start.")
+	}
+
+	if lr.Count < 0 {
+		return errors.New("Loop counts can't be negative.")
+	}
+
+	return nil
+}
+
+// Getting the time in seconds from the start of a NoteArrangement until its
+// last note stops being held, not counting release tails.
+func (na NoteArrangement) Length() float32 {
+	var start, length float32
+
+	for _, dnd := range na {
+		start += dnd.Delay
+		if start+dnd.ND.Duration > length {
+			length = start + dnd.ND.Duration
+		}
+	}
+
+	return length
+}
+
+// Looping a region of the notes in a PrimaryDriver.
+func (pd *PrimaryDriver) SetLoop(region LoopRegion) error {
+	if err := region.Validate(); err != nil {
+		return err
+	}
+
+	pd.loop = &region
+	pd.loopPlays = 1
+	pd.loopDirty = true
+
+	return nil
+}
+
+// Stopping a PrimaryDriver from looping, letting it play on past the end of
+// the region.
+func (pd *PrimaryDriver) ClearLoop() {
+	pd.loop = nil
+}
+
+// Checking if a PrimaryDriver still has to go back to the start of its loop
+// region at least once more. A region with nothing in it, like one starting
+// after the last note, is never looped, so that playback can still finish.
+func (pd *PrimaryDriver) looping() bool {
+	if pd.loop == nil || (pd.loop.Count != LoopForever && pd.loopPlays >= pd.loop.Count) {
+		return false
+	}
+
+	if pd.loopDirty {
+		pd.resolveLoop()
+	}
+
+	return pd.loopEnd > pd.loopStart
+}
+
+// Working out the samples a loop region starts and ends on. With no end given
+// this depends on the notes that have been scheduled, so it's only done again
+// when they change.
+func (pd *PrimaryDriver) resolveLoop() {
+	rate := float64(pd.SampleRate)
+
+	pd.loopStart = int64(math.Floor(float64(pd.loop.Start)*rate + 0.5))
+	pd.loopEnd = int64(math.Floor(float64(pd.loop.End)*rate + 0.5))
+
+	if pd.loop.End == 0 {
+		pd.loopEnd = pd.forgotten
+		for _, sn := range pd.ScheduledNotes {
+			end := sn.Start + int64(math.Floor(float64(sn.ND.Duration)*rate+0.5))
+			if end > pd.loopEnd {
+				pd.loopEnd = end
+			}
+		}
+	}
+
+	pd.loopDirty = false
+}
+
+// Going back to the start of the loop region once playback reaches its end.
+// Voices that are still sounding are left to finish, so that there's no gap
+// at the loop boundary.
+func (pd *PrimaryDriver) wrapLoop() {
+	if !pd.looping() || pd.Sample < pd.loopEnd {
+		return
+	}
+
+	pd.Sample = pd.loopStart
+	pd.next = sort.Search(len(pd.ScheduledNotes), func(i int) bool {
+		return pd.ScheduledNotes[i].Start >= pd.loopStart
+	})
+	pd.loopPlays++
+}
+
+// Looping a region of the notes being played.
+func (c *Controller) SetLoop(region LoopRegion) error {
+	if err := region.Validate(); err != nil {
+		return err
+	}
+
+	c.send(func(pd *PrimaryDriver) {
+		pd.SetLoop(region)
+	})
+
+	return nil
+}
+
+// Stopping the notes being played from looping.
+func (c *Controller) ClearLoop() {
+	c.send((*PrimaryDriver).ClearLoop)
+}
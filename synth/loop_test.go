@@ -0,0 +1,117 @@
+package synth
+
+import (
+	"testing"
+)
+
+// The sample rate loop tests run at, low enough that seconds are easy to count
+// in samples.
+const loopTestRate int = 1000
+
+// Making a driver with one held note starting every second, for a given number
+// of seconds.
+func loopTestDriver(notes int) *PrimaryDriver {
+	pd := NewPrimaryDriverEmpty()
+	nd := NoteData{Duration: 0.5, Volume: 1, Frequency: 440, Envelope: Envelope{Sustain: 1}}
+
+	for i := 0; i < notes; i++ {
+		pd.AddDelayedNote(DelayedNoteData{Delay: 1, ND: nd})
+	}
+
+	return pd
+}
+
+// Playing a driver until it finishes, or until a limit on the samples played,
+// recording the sample every note is started on.
+func playLoop(pd *PrimaryDriver, limit int) ([]int64, int) {
+	starts := []int64{}
+
+	samples := 0
+	for ; samples < limit && !pd.Finished(); samples++ {
+		pd.CalculateOutput()
+		pd.StepPhases(loopTestRate)
+
+		// Voices started by this step have only been stepped once.
+		for _, sd := range pd.CurrentNotes {
+			if sd.Samples == 1 {
+				starts = append(starts, sd.Start)
+			}
+		}
+	}
+
+	return starts, samples
+}
+
+func TestLoopBoundary(t *testing.T) {
+	pd := loopTestDriver(4)
+	if err := pd.SetLoop(LoopRegion{Start: 2, End: 4, Count: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The note on the end of the region is only played once the region has
+	// been played through, and the note on its start is played on every pass.
+	starts, samples := playLoop(pd, 100*loopTestRate)
+	want := []int64{1000, 2000, 3000, 2000, 3000, 2000, 3000, 4000}
+	if len(starts) != len(want) {
+		t.Fatalf("Expected starts %v, got %v.", want, starts)
+	}
+
+	for i := range want {
+		if starts[i] != want[i] {
+			t.Fatalf("Expected starts %v, got %v.", want, starts)
+		}
+	}
+
+	// One second before the region, three passes of two seconds, and the last
+	// note with its release.
+	if samples < 8500 || samples > 8510 {
+		t.Errorf("Expected playback to finish after around 8500 samples, got %d.", samples)
+	}
+}
+
+func TestLoopWithoutNotes(t *testing.T) {
+	tests := []struct {
+		name   string
+		notes  int
+		region LoopRegion
+	}{
+		{"start after the last note", 1, LoopRegion{Start: 5, Count: 2}},
+		{"start after the last note, forever", 1, LoopRegion{Start: 5, Count: LoopForever}},
+		{"empty song", 0, LoopRegion{Count: 2}},
+		{"empty song, forever", 0, LoopRegion{Count: LoopForever}},
+	}
+
+	for _, test := range tests {
+		pd := loopTestDriver(test.notes)
+		if err := pd.SetLoop(test.region); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, samples := playLoop(pd, 100*loopTestRate); samples >= 100*loopTestRate {
+			t.Errorf("%s: playback never finished.", test.name)
+		}
+	}
+}
+
+func TestLoopAfterTheNotes(t *testing.T) {
+	// A region after the notes with its own end is played as silence, and
+	// still finishes. Like playback without a loop, the last pass ends as soon
+	// as there's nothing left to play.
+	pd := loopTestDriver(1)
+	if err := pd.SetLoop(LoopRegion{Start: 5, End: 6, Count: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, samples := playLoop(pd, 100*loopTestRate); samples != 6*loopTestRate {
+		t.Errorf("Expected playback to finish after %d samples, got %d.", 6*loopTestRate, samples)
+	}
+}
+
+func TestNoteArrangementLength(t *testing.T) {
+	nd := NoteData{Duration: 0.5}
+	na := NoteArrangement{{Delay: 1, ND: nd}, {Delay: 1, ND: nd}}
+
+	if na.Length() != 2.5 {
+		t.Errorf("Expected a length of 2.5, got %f.", na.Length())
+	}
+}
@@ -1,14 +1,18 @@
 package synth
 
 // The function to start a synth with the intent of being asynchronous WITH a
-// given slice of starting notes.
+// given slice of starting notes. It returns straight away with a Controller
+// for the synth it started.
 //
-// na           - The slice of notes to play.
-// iNoteChannel - A channel to provide note data.
-// iQuitChannel - A channel to query for an external exit signal.
-// oErrChannel  - A channel to send out error information to the calling
-//                function.
-func StartSynthAsyncWith(na *NoteArrangement, iNoteChannel chan DelayedNoteData, ioQuitChannel chan bool, oErrChannel chan error, quitWhenDone bool) {
+// sink          - Where to send the synthesized audio.
+// settings      - The sample rate, buffer size and other options to run with.
+// na            - The slice of notes to play.
+// iNoteChannel  - A channel to provide note data.
+// ioQuitChannel - A channel to query for an external exit signal, which is
+//                 also signalled when the synth finishes by itself.
+// oErrChannel   - A channel to send out error information to the calling
+//                 function.
+func StartSynthAsyncWith(sink Sink, settings Settings, na *NoteArrangement, iNoteChannel chan DelayedNoteData, ioQuitChannel chan bool, oErrChannel chan error, quitWhenDone bool) *Controller {
 	var pd *PrimaryDriver
 	if na == nil {
 		pd = NewPrimaryDriverEmpty()
@@ -16,31 +20,80 @@ func StartSynthAsyncWith(na *NoteArrangement, iNoteChannel chan DelayedNoteData,
 		pd = NewPrimaryDriver(*na)
 	}
 
-	exitChannel := make(chan bool)
-	defer close(exitChannel)
+	controller := newController(pd, settings.SampleRate)
+	go runSynth(sink, settings, pd, controller, iNoteChannel, ioQuitChannel, oErrChannel, quitWhenDone)
 
+	return controller
+}
+
+// Running a PrimaryDriver through a sink, feeding it notes until it's told to
+// quit or it finishes.
+func runSynth(sink Sink, settings Settings, pd *PrimaryDriver, controller *Controller, iNoteChannel chan DelayedNoteData, ioQuitChannel chan bool, oErrChannel chan error, quitWhenDone bool) {
+	defer close(controller.done)
+
+	if err := settings.Validate(); err != nil {
+		oErrChannel <- err
+		return
+	}
+	pd.ApplySettings(settings)
+
+	exitChannel := make(chan bool)
 	errChannel := make(chan error)
-	defer close(errChannel)
+	sinkDone := make(chan bool)
+
+	go func() {
+		defer close(sinkDone)
+		sink.Run(pd, settings, errChannel, quitWhenDone, exitChannel)
+	}()
 
-	go RunSynth(pd, errChannel, quitWhenDone, exitChannel)
+	// Telling the sink to exit and waiting for it to return, so that nothing is
+	// still being written once the synth is done. The sink might be busy
+	// sending on exitChannel or errChannel itself, so both are drained until it
+	// returns.
+	stopSink := func() {
+		for {
+			select {
+			case exitChannel <- true:
+			case _ = <-exitChannel:
+			case _ = <-errChannel:
+			case _ = <-sinkDone:
+				return
+			}
+		}
+	}
+
+	// Notes are handed to the driver through its inbox rather than added
+	// directly, because the sink owns the driver from here on. While a note is
+	// waiting to go into the inbox, in is nil and out is set, so that a full
+	// inbox holds up new notes without holding up the other channels.
+	var dnd DelayedNoteData
+	var out chan<- DelayedNoteData
+	in := iNoteChannel
 
-	// Using a label here so we can break out of the for loop from inside the
-	// case statement.
-	//
 	// The case statement is used so we can aggressively scan for information
-	// from all three channels.
+	// from all of the channels.
 	for {
 		select {
-		case dnd := <-iNoteChannel:
-			pd.AddDelayedNote(dnd)
+		case dnd = <-in:
+			in, out = nil, pd.Inbox()
+		case out <- dnd:
+			in, out = iNoteChannel, nil
 		case _ = <-ioQuitChannel:
+			stopSink()
+			return
+		case _ = <-controller.stop:
+			stopSink()
 			return
 		case _ = <-exitChannel:
+			stopSink()
+			ioQuitChannel <- true
+			return
+		case _ = <-sinkDone:
 			ioQuitChannel <- true
 			return
 		case err := <-errChannel:
 			if err != nil {
-				exitChannel <- true
+				stopSink()
 				oErrChannel <- err
 				return
 			}
@@ -48,30 +101,35 @@ func StartSynthAsyncWith(na *NoteArrangement, iNoteChannel chan DelayedNoteData,
 	}
 }
 
-// The function to start a synth with the intent of being asynchronous.
+// The function to start a synth with the intent of being asynchronous. It
+// returns straight away with a Controller for the synth it started.
 //
+// sink         - Where to send the synthesized audio.
+// settings     - The sample rate, buffer size and other options to run with.
 // iNoteChannel - A channel to provide note data.
 // iQuitChannel - A channel to query for an external exit signal.
 // oErrChannel  - A channel to send out error information to the calling
 //                function.
-func StartSynthAsync(iNoteChannel chan DelayedNoteData, iQuitChannel chan bool, oErrChannel chan error) {
-	StartSynthAsyncWith(nil, iNoteChannel, iQuitChannel, oErrChannel, false)
+func StartSynthAsync(sink Sink, settings Settings, iNoteChannel chan DelayedNoteData, iQuitChannel chan bool, oErrChannel chan error) *Controller {
+	return StartSynthAsyncWith(sink, settings, nil, iNoteChannel, iQuitChannel, oErrChannel, false)
 }
 
 // Starting a synth with a beginning note arrangement.
-func StartSynthWith(na *NoteArrangement, iNoteChannel chan DelayedNoteData, quitWhenDone bool) error {
+func StartSynthWith(sink Sink, settings Settings, na *NoteArrangement, iNoteChannel chan DelayedNoteData, quitWhenDone bool) error {
 	iQuitChannel := make(chan bool)
 	defer close(iQuitChannel)
 
 	errChannel := make(chan error)
 	defer close(errChannel)
 
-	go StartSynthAsyncWith(na, iNoteChannel, iQuitChannel, errChannel, quitWhenDone)
+	controller := StartSynthAsyncWith(sink, settings, na, iNoteChannel, iQuitChannel, errChannel, quitWhenDone)
 
 	for {
 		select {
 		case _ = <-iQuitChannel:
 			return nil
+		case _ = <-controller.Done():
+			return nil
 		case err := <-errChannel:
 			if err != nil {
 				return err
@@ -83,6 +141,6 @@ func StartSynthWith(na *NoteArrangement, iNoteChannel chan DelayedNoteData, quit
 }
 
 // Starting the synth with a channel for note data.
-func StartSynth(noteChannel chan DelayedNoteData) error {
-	return StartSynthWith(nil, noteChannel, false)
+func StartSynth(sink Sink, settings Settings, noteChannel chan DelayedNoteData) error {
+	return StartSynthWith(sink, settings, nil, noteChannel, false)
 }
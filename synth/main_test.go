@@ -0,0 +1,130 @@
+package synth
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A sink that takes a while to finish once told to exit, and fails while it
+// does, like a FileSink that can't patch its header.
+type slowSink struct {
+	finished *int32
+}
+
+func (s slowSink) Run(driver Driver, settings Settings, errChannel chan error, quitWhenDone bool, exitChannel chan bool) {
+	<-exitChannel
+	time.Sleep(20 * time.Millisecond)
+
+	errChannel <- errors.New("Failed to finish.")
+	<-exitChannel
+
+	atomic.StoreInt32(s.finished, 1)
+}
+
+func TestStopWaitsForSink(t *testing.T) {
+	var finished int32
+
+	notes := make(chan DelayedNoteData)
+	quit := make(chan bool)
+	errs := make(chan error, 1)
+
+	controller := StartSynthAsync(slowSink{&finished}, DefaultSettings(), notes, quit, errs)
+	controller.Stop()
+
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("Stopping returned before the sink finished.")
+	}
+}
+
+func TestQuitWaitsForSink(t *testing.T) {
+	var finished int32
+
+	notes := make(chan DelayedNoteData)
+	quit := make(chan bool)
+	errs := make(chan error, 1)
+
+	controller := StartSynthAsync(slowSink{&finished}, DefaultSettings(), notes, quit, errs)
+	quit <- true
+	<-controller.Done()
+
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("The synth was done before the sink finished.")
+	}
+}
+
+// Run with -race: notes are sent from one goroutine while the NullSink steps
+// the driver on another, and only the inbox is shared between them.
+func TestInboxWhileRendering(t *testing.T) {
+	notes := make(chan DelayedNoteData)
+	quit := make(chan bool)
+	errs := make(chan error, 1)
+
+	settings := DefaultSettings()
+	settings.BufferSize = 64
+	settings.MaxVoices = 8
+
+	controller := StartSynthAsync(NullSink{}, settings, notes, quit, errs)
+
+	sent := make(chan bool)
+	go func() {
+		defer close(sent)
+
+		for i := 0; i < 500; i++ {
+			dnd, err := MakeNoteData(RawDelayedNoteData{Note: "A4", Duration: 0.01, Instrument: "guitar"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			notes <- dnd
+		}
+	}()
+
+	<-sent
+	for start := controller.Position(); controller.Position()-start < 0.05; {
+		time.Sleep(time.Millisecond)
+	}
+	controller.Stop()
+
+	// Stopping waits for the sink, so the driver can be looked at from here.
+	pd := controller.pd
+	if len(pd.inbox) != 0 || len(pd.QueuedNotes) != 0 {
+		t.Errorf("Expected every note to be taken from the inbox, but %d are waiting.", len(pd.inbox)+len(pd.QueuedNotes))
+	}
+}
+
+// Waiting for playback to move on by a number of seconds.
+func waitFor(controller *Controller, seconds float32) {
+	for start := controller.Position(); controller.Position()-start < seconds; {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestControllerMeter(t *testing.T) {
+	notes := make(chan DelayedNoteData, 1)
+	quit := make(chan bool)
+	errs := make(chan error, 1)
+
+	controller := StartSynthAsync(NullSink{}, DefaultSettings(), notes, quit, errs)
+	defer controller.Stop()
+
+	dnd, err := MakeNoteData(RawDelayedNoteData{Note: "A4", Duration: 10, Instrument: "guitar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes <- dnd
+
+	// A couple of meter windows later the note is being measured.
+	waitFor(controller, 0.2)
+	if peak, rms := controller.Peak(), controller.RMS(); !(peak > 0 && peak <= 1) || !(rms > 0 && rms <= peak) {
+		t.Errorf("Expected a peak in (0, 1] over the RMS, got a peak of %v and an RMS of %v.", peak, rms)
+	}
+
+	controller.SetGain(0)
+	waitFor(controller, 0.2)
+	if peak, rms := controller.Peak(), controller.RMS(); peak != 0 || rms != 0 {
+		t.Errorf("Expected silence without any gain, got a peak of %v and an RMS of %v.", peak, rms)
+	}
+}
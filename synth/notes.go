@@ -5,111 +5,178 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 )
 
 // The base note to be used for the other calculations.
 const A4 float32 = 440
 const A4I int = 57
 
-// Converting the int-representation of a note to the string-representation of
-// a note.
-func NoteToString(note int) string {
-	octave := note / 12
-
-	var str string
-	switch note - (octave * 12) {
-	case 0:
-		str = "C"
-	case 1:
-		str = "C#"
-	case 2:
-		str = "D"
-	case 3:
-		str = "D#"
-	case 4:
-		str = "E"
-	case 5:
-		str = "F"
-	case 6:
-		str = "F#"
-	case 7:
-		str = "G"
-	case 8:
-		str = "G#"
-	case 9:
-		str = "A"
-	case 10:
-		str = "A#"
-	case 11:
-		str = "B"
-	}
-
-	return fmt.Sprintf("%s%d", str, octave)
+// The names of the notes in an octave, spelled with sharps.
+var noteNames = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// The place of each natural note in an octave.
+var naturals = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// The number of semitones each accidental moves a note by.
+var accidentals = map[string]int{"": 0, "#": 1, "b": -1, "##": 2, "x": 2, "bb": -2}
+
+// Type Pitch is a note as it's written in a song - either a note name, with an
+// optional offset in cents, or a literal frequency.
+type Pitch struct {
+	Note       int     // The note, in the numbering used by NoteToInt.
+	Accidental string  // The accidental Note was written with, kept so it can be spelled the same way.
+	Cents      float32 // The offset from Note in cents.
+	Frequency  float32 // A literal frequency in Hz, used in place of Note and Cents when above 0.
 }
 
-// Converting the string-representation of a note to the int-representation of a
-// note.
-func NoteToInt(note string) (int, error) {
-	if len(note) < 2 {
-		return 0, errors.New("Malformed note: " + note)
+// Parsing a Pitch from its string representation, which is one of:
+//
+// A note name - a letter, an accidental (#, b, ##, x or bb) and an octave, like
+// "C4", "Bb3", "F##2" or "C-1".
+//
+// A note name with an offset - a note name followed by a signed number of
+// cents, like "A4+15c" or "Eb4-31.3c".
+//
+// A frequency - a number followed by "Hz", like "440Hz" or "261.63Hz".
+func ParsePitch(str string) (Pitch, error) {
+	if strings.HasSuffix(str, "Hz") {
+		frequency, err := strconv.ParseFloat(str[:len(str)-2], 32)
+		if err != nil || !(frequency > 0) || math.IsInf(frequency, 0) {
+			return Pitch{}, errors.New("Malformed frequency: " + str)
+		}
+
+		return Pitch{Frequency: float32(frequency)}, nil
+	}
+
+	if len(str) < 2 {
+		return Pitch{}, errors.New("Malformed note: " + str)
+	}
+
+	natural, ok := naturals[str[0]]
+	if !ok {
+		return Pitch{}, errors.New("Invalid note name: " + str)
+	}
+
+	// Finding the end of the accidental, where the octave starts.
+	edge := 1
+	for edge < len(str) && (str[edge] == '#' || str[edge] == 'b' || str[edge] == 'x') {
+		edge++
+	}
+
+	accidental, ok := accidentals[str[1:edge]]
+	if !ok {
+		return Pitch{}, errors.New("Invalid accidental: " + str[1:edge])
+	}
+
+	// Finding the end of the octave, where the cents start. The octave can
+	// have a leading '-', so it's skipped before looking for a sign.
+	end := edge
+	if end < len(str) && str[end] == '-' {
+		end++
+	}
+	for end < len(str) && '0' <= str[end] && str[end] <= '9' {
+		end++
+	}
+
+	octave, err := strconv.Atoi(str[edge:end])
+	if err != nil {
+		return Pitch{}, errors.New("Malformed octave: " + str)
+	}
+
+	var cents float64
+	if end < len(str) {
+		offset := str[end:]
+		if !strings.HasSuffix(offset, "c") || (offset[0] != '+' && offset[0] != '-') {
+			return Pitch{}, errors.New("Malformed cent offset: " + str)
+		}
+
+		cents, err = strconv.ParseFloat(offset[:len(offset)-1], 32)
+		if err != nil || math.IsNaN(cents) || math.IsInf(cents, 0) {
+			return Pitch{}, errors.New("Malformed cent offset: " + str)
+		}
 	}
 
-	var edge int
-	for edge = 0; edge < len(note); edge++ {
-		if '0' <= note[edge] && note[edge] <= '9' {
-			break
+	return Pitch{Note: octave*12 + natural + accidental, Accidental: str[1:edge], Cents: float32(cents)}, nil
+}
+
+// Converting a Pitch to its string representation. Notes are spelled with the
+// accidental they were written with, or with sharps when that would no longer
+// land on a natural note.
+func (p Pitch) String() string {
+	if p.Frequency > 0 {
+		return strconv.FormatFloat(float64(p.Frequency), 'f', -1, 32) + "Hz"
+	}
+
+	str := NoteToString(p.Note)
+	if offset, ok := accidentals[p.Accidental]; ok && p.Accidental != "" {
+		octave, n := floorDiv(p.Note-offset, 12)
+		if name := noteNames[n]; len(name) == 1 {
+			str = fmt.Sprintf("%s%s%d", name, p.Accidental, octave)
 		}
 	}
 
-	oct, err := strconv.ParseInt(note[edge:], 10, 0)
+	if p.Cents != 0 {
+		str += fmt.Sprintf("%+gc", p.Cents)
+	}
+
+	return str
+}
+
+// Getting the frequency of a Pitch in a Tuning.
+func (p Pitch) In(tuning Tuning) float32 {
+	if p.Frequency > 0 {
+		return p.Frequency
+	}
+
+	frequency := tuning.Frequency(p.Note)
+	if p.Cents == 0 {
+		return frequency
+	}
+
+	return float32(float64(frequency) * math.Exp2(float64(p.Cents)/1200))
+}
+
+// Getting the whole note closest to a Pitch. Literal frequencies are placed in
+// the StandardTuning.
+func (p Pitch) Nearest() int {
+	if p.Frequency > 0 {
+		return A4I + int(math.Floor(12*math.Log2(float64(p.Frequency/A4))+0.5))
+	}
+
+	return p.Note + int(math.Floor(float64(p.Cents)/100+0.5))
+}
+
+// Converting the int-representation of a note to the string-representation of
+// a note.
+func NoteToString(note int) string {
+	octave, n := floorDiv(note, 12)
+	return fmt.Sprintf("%s%d", noteNames[n], octave)
+}
+
+// Converting the string-representation of a note to the int-representation of a
+// note. Anything ParsePitch accepts can be given, and pitches that fall between
+// notes are rounded to the nearest one.
+func NoteToInt(note string) (int, error) {
+	p, err := ParsePitch(note)
 	if err != nil {
 		return 0, err
 	}
 
-	var n int
-	switch note[:edge] {
-	case "C":
-		n = 0
-	case "C#":
-		n = 1
-	case "D":
-		n = 2
-	case "D#":
-		n = 3
-	case "E":
-		n = 4
-	case "F":
-		n = 5
-	case "F#":
-		n = 6
-	case "G":
-		n = 7
-	case "G#":
-		n = 8
-	case "A":
-		n = 9
-	case "A#":
-		n = 10
-	case "B":
-		n = 11
-	default:
-		return 0, errors.New("Invalid note name: " + note[:edge])
-	}
-
-	return int(oct)*12 + n, nil
+	return p.Nearest(), nil
 }
 
-// Calculating the frequency of a given note.
+// Calculating the frequency of a given note in the StandardTuning.
 func CalculateFrequency(note int) float32 {
-	return A4 * float32(math.Pow(1.059463094359, float64(note-A4I)))
+	return StandardTuning.Frequency(note)
 }
 
 // Calculating the frequency of a given note from its string representation.
 func CalculateFrequencyStr(note string) (float32, error) {
-	n, err := NoteToInt(note)
+	p, err := ParsePitch(note)
 	if err != nil {
 		return 0.0, err
 	}
 
-	return CalculateFrequency(n), nil
+	return p.In(StandardTuning), nil
 }
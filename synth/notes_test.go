@@ -0,0 +1,89 @@
+package synth
+
+import (
+	"testing"
+)
+
+func TestParsePitch(t *testing.T) {
+	tests := []struct {
+		str    string
+		pitch  Pitch
+		string string
+	}{
+		{"C4", Pitch{Note: 48}, "C4"},
+		{"A#4", Pitch{Note: 58, Accidental: "#"}, "A#4"},
+		{"Bb3", Pitch{Note: 46, Accidental: "b"}, "Bb3"},
+		{"Cb4", Pitch{Note: 47, Accidental: "b"}, "Cb4"},
+		{"B#3", Pitch{Note: 48, Accidental: "#"}, "B#3"},
+		{"F##2", Pitch{Note: 31, Accidental: "##"}, "F##2"},
+		{"Fx2", Pitch{Note: 31, Accidental: "x"}, "Fx2"},
+		{"Dbb3", Pitch{Note: 36, Accidental: "bb"}, "Dbb3"},
+		{"C-1", Pitch{Note: -12}, "C-1"},
+		{"Eb-1", Pitch{Note: -9, Accidental: "b"}, "Eb-1"},
+		{"C10", Pitch{Note: 120}, "C10"},
+		{"A4+15c", Pitch{Note: 57, Cents: 15}, "A4+15c"},
+		{"A4-15c", Pitch{Note: 57, Cents: -15}, "A4-15c"},
+		{"Eb4-31.5c", Pitch{Note: 51, Accidental: "b", Cents: -31.5}, "Eb4-31.5c"},
+		{"C-1-10c", Pitch{Note: -12, Cents: -10}, "C-1-10c"},
+		{"440Hz", Pitch{Frequency: 440}, "440Hz"},
+		{"261.63Hz", Pitch{Frequency: 261.63}, "261.63Hz"},
+	}
+
+	for _, test := range tests {
+		pitch, err := ParsePitch(test.str)
+		if err != nil {
+			t.Errorf("%s: %s", test.str, err)
+			continue
+		}
+
+		if pitch != test.pitch {
+			t.Errorf("%s: expected %+v, got %+v.", test.str, test.pitch, pitch)
+		}
+
+		if str := pitch.String(); str != test.string {
+			t.Errorf("%s: expected it to be written as %s, got %s.", test.str, test.string, str)
+		}
+	}
+}
+
+func TestParsePitchErrors(t *testing.T) {
+	tests := []string{
+		"", "C", "c4", "H4", "C#b4", "Cbbb4", "C-", "C4+15", "C4 15c", "A4+c", "C4+Infc",
+		"Hz", "xHz", "0Hz", "-5Hz", "infHz", "NaNHz",
+	}
+
+	for _, test := range tests {
+		if pitch, err := ParsePitch(test); err == nil {
+			t.Errorf("%q: expected an error, got %+v.", test, pitch)
+		}
+	}
+}
+
+func TestPitchString(t *testing.T) {
+	tests := []struct {
+		pitch  Pitch
+		string string
+	}{
+		// Pitches without an accidental are spelled with sharps.
+		{Pitch{Note: 46}, "A#3"},
+		{Pitch{Note: -1}, "B-1"},
+		{Pitch{Note: 57, Cents: 0.5}, "A4+0.5c"},
+
+		// Accidentals that no longer land on a natural note fall back to
+		// sharps.
+		{Pitch{Note: 47, Accidental: "#"}, "B3"},
+		{Pitch{Note: 49, Accidental: "bb"}, "C#4"},
+	}
+
+	for _, test := range tests {
+		if str := test.pitch.String(); str != test.string {
+			t.Errorf("%+v: expected %s, got %s.", test.pitch, test.string, str)
+		}
+	}
+
+	for note := -24; note < 140; note++ {
+		if got, err := NoteToInt(NoteToString(note)); err != nil || got != note {
+			t.Errorf("%d: written as %s, read back as %d.", note, NoteToString(note), got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package synth
+
+import (
+	"errors"
+	"math"
+)
+
+// Type PlaybackOptions holds the changes made to a song as it's turned into a
+// NoteArrangement, so that it can be practised at a different speed, in a
+// different key or in a different tuning without changing the song itself.
+type PlaybackOptions struct {
+	Tempo     float32 // The speed to play at, where 1 is the written speed and 0.7 is 70% of it.
+	Semitones int     // The number of semitones to transpose every note by.
+	Cents     float32 // The number of cents to transpose every note by, on top of Semitones.
+	Tuning    Tuning  // The tuning to work out frequencies in, or nil for the StandardTuning.
+}
+
+// Creating the default set of PlaybackOptions, which play a song as written.
+func DefaultPlaybackOptions() PlaybackOptions {
+	return PlaybackOptions{
+		Tempo:     1,
+		Semitones: 0,
+		Cents:     0,
+		Tuning:    StandardTuning,
+	}
+}
+
+// Checking that a set of PlaybackOptions can be played with.
+func (po PlaybackOptions) Validate() error {
+	if !(po.Tempo > 0) || math.IsInf(float64(po.Tempo), 0) {
+		return errors.New("Tempo must be a finite number greater than 0.")
+	} else if math.IsNaN(float64(po.Cents)) || math.IsInf(float64(po.Cents), 0) {
+		return errors.New("Cents must be a finite number.")
+	}
+
+	return nil
+}
+
+// Scaling a delay or duration in seconds by the tempo.
+func (po PlaybackOptions) ScaleTime(seconds float32) float32 {
+	return seconds / po.Tempo
+}
+
+// Getting the frequency of a Pitch in the tuning, after transposing it. Notes
+// move by semitones through the tuning itself, so that a transposed song is
+// still in tune with itself, and then by cents from there. Literal frequencies
+// move by the equal tempered size of both.
+func (po PlaybackOptions) Frequency(p Pitch) float32 {
+	tuning := po.Tuning
+	if tuning == nil {
+		tuning = StandardTuning
+	}
+
+	cents := float64(po.Cents)
+	if p.Frequency > 0 {
+		cents += float64(po.Semitones) * 100
+	} else {
+		p.Note += po.Semitones
+	}
+
+	frequency := p.In(tuning)
+	if cents == 0 {
+		return frequency
+	}
+
+	return float32(float64(frequency) * math.Exp2(cents/1200))
+}
@@ -0,0 +1,37 @@
+package synth
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPlaybackOptionsValidate(t *testing.T) {
+	nan := float32(math.NaN())
+	inf := float32(math.Inf(1))
+
+	tests := []struct {
+		name  string
+		tempo float32
+		cents float32
+		valid bool
+	}{
+		{"Written speed", 1, 0, true},
+		{"Slowed down", 0.7, -15, true},
+		{"Zero tempo", 0, 0, false},
+		{"Negative tempo", -1, 0, false},
+		{"NaN tempo", nan, 0, false},
+		{"Infinite tempo", inf, 0, false},
+		{"NaN cents", 1, nan, false},
+		{"Infinite cents", 1, -inf, false},
+	}
+
+	for _, test := range tests {
+		options := DefaultPlaybackOptions()
+		options.Tempo = test.tempo
+		options.Cents = test.cents
+
+		if err := options.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid to be %v, got the error %v.", test.name, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package synth
+
+import (
+	"github.com/crockeo/go-tuner/filestore/wav"
+	"io"
+)
+
+// Rendering a driver offline, without any audio device, by handing each frame
+// of output to a function until the driver is finished.
+func Render(driver Driver, sampleRate int, out func([]float32) error) error {
+	for !driver.Finished() {
+		if err := out(driver.CalculateOutput()); err != nil {
+			return err
+		}
+
+		driver.StepPhases(sampleRate)
+	}
+
+	return nil
+}
+
+// Rendering a driver offline into a WAV file with a given sample encoding.
+func RenderWAV(driver Driver, sampleRate int, writer io.WriteSeeker, encoding wav.Encoding) error {
+	w, err := wav.NewWriter(writer, wav.Format{
+		Encoding:   encoding,
+		Channels:   driver.OutputChannels(),
+		SampleRate: sampleRate,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = Render(driver, sampleRate, w.WriteFrame); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
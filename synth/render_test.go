@@ -0,0 +1,171 @@
+package synth
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/crockeo/go-tuner/filestore/wav"
+	"io"
+	"math"
+	"testing"
+)
+
+// The sample rate render tests run at.
+const renderTestRate int = 8000
+
+// An in memory io.WriteSeeker to render into.
+type memoryFile struct {
+	data   []byte
+	offset int64
+}
+
+func (m *memoryFile) Write(p []byte) (int, error) {
+	if end := m.offset + int64(len(p)); end > int64(len(m.data)) {
+		m.data = append(m.data, make([]byte, end-int64(len(m.data)))...)
+	}
+
+	copy(m.data[m.offset:], p)
+	m.offset += int64(len(p))
+
+	return len(p), nil
+}
+
+func (m *memoryFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		m.offset = offset
+	case io.SeekCurrent:
+		m.offset += offset
+	case io.SeekEnd:
+		m.offset = int64(len(m.data)) + offset
+	}
+
+	if m.offset < 0 {
+		return 0, errors.New("Seeking before the start of the file.")
+	}
+
+	return m.offset, nil
+}
+
+// Making the driver for a short, fixed arrangement.
+func renderTestDriver(t *testing.T) *PrimaryDriver {
+	na, err := MakeNoteArrangement([]RawDelayedNoteData{
+		{Delay: 0, Note: "A4", Duration: 0.05, Instrument: "guitar"},
+		{Delay: 0.025, Note: "C5", Duration: 0.05, Instrument: "guitar", Pan: -0.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewPrimaryDriver(*na)
+}
+
+func TestRenderWAV(t *testing.T) {
+	// Rendering the arrangement without a file, to know what every sample
+	// should be.
+	frames := [][]float32{}
+	err := Render(renderTestDriver(t), renderTestRate, func(frame []float32) error {
+		frames = append(frames, append([]float32{}, frame...))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var peak float64
+	for _, frame := range frames {
+		for _, s := range frame {
+			peak = math.Max(peak, math.Abs(float64(s)))
+		}
+	}
+
+	if peak == 0 {
+		t.Fatal("Expected the arrangement to render something other than silence.")
+	}
+
+	tests := []struct {
+		encoding   wav.Encoding
+		tag        uint16
+		sampleSize int
+		fmtLength  uint32
+	}{
+		{wav.PCM16, 1, 2, 16},
+		{wav.Float32, 3, 4, 18},
+	}
+
+	for _, test := range tests {
+		file := new(memoryFile)
+		if err := RenderWAV(renderTestDriver(t), renderTestRate, file, test.encoding); err != nil {
+			t.Fatal(err)
+		}
+
+		data := file.data
+		u16 := func(offset int) uint16 { return binary.LittleEndian.Uint16(data[offset:]) }
+		u32 := func(offset int) uint32 { return binary.LittleEndian.Uint32(data[offset:]) }
+
+		blockAlign := 2 * test.sampleSize
+		dataLength := uint32(len(frames) * blockAlign)
+
+		// Float32 files carry an extension size and a fact chunk between the
+		// fmt and data chunks.
+		dataChunk := 12 + 8 + int(test.fmtLength)
+		if test.encoding == wav.Float32 {
+			if u16(36) != 0 {
+				t.Errorf("%d: expected an empty fmt extension, got %d bytes.", test.encoding, u16(36))
+			}
+
+			if string(data[38:42]) != "fact" || u32(42) != 4 || u32(46) != uint32(len(frames)) {
+				t.Errorf("%d: expected a fact chunk with %d frames.", test.encoding, len(frames))
+			}
+
+			dataChunk += 12
+		}
+
+		header := []struct {
+			name      string
+			got, want uint32
+		}{
+			{"RIFF size", u32(4), uint32(len(data) - 8)},
+			{"fmt size", u32(16), test.fmtLength},
+			{"format tag", uint32(u16(20)), uint32(test.tag)},
+			{"channels", uint32(u16(22)), 2},
+			{"sample rate", u32(24), uint32(renderTestRate)},
+			{"byte rate", u32(28), uint32(renderTestRate * blockAlign)},
+			{"block align", uint32(u16(32)), uint32(blockAlign)},
+			{"bits per sample", uint32(u16(34)), uint32(test.sampleSize * 8)},
+			{"data size", u32(dataChunk + 4), dataLength},
+			{"file size", uint32(len(data)), uint32(dataChunk+8) + dataLength},
+		}
+
+		if string(data[0:4]) != "RIFF" || string(data[8:16]) != "WAVEfmt " || string(data[dataChunk:dataChunk+4]) != "data" {
+			t.Fatalf("%d: malformed chunk names.", test.encoding)
+		}
+
+		for _, h := range header {
+			if h.got != h.want {
+				t.Errorf("%d: expected a %s of %d, got %d.", test.encoding, h.name, h.want, h.got)
+			}
+		}
+
+		if t.Failed() {
+			continue
+		}
+
+		offset := dataChunk + 8
+		for i, frame := range frames {
+			for c, s := range frame {
+				var got, want uint32
+				if test.encoding == wav.Float32 {
+					got, want = u32(offset), math.Float32bits(s)
+				} else {
+					got, want = uint32(u16(offset)), uint32(uint16(int16(float32(math.Max(-1, math.Min(1, float64(s))))*math.MaxInt16)))
+				}
+
+				if got != want {
+					t.Fatalf("%d: frame %d, channel %d: expected sample %#x, got %#x.", test.encoding, i, c, want, got)
+				}
+
+				offset += test.sampleSize
+			}
+		}
+	}
+}
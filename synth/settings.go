@@ -0,0 +1,55 @@
+package synth
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	DefaultSampleRate int = 44100  // The sample rate used when none is given.
+	MinSampleRate     int = 8000   // The lowest sample rate a synth will run at.
+	MaxSampleRate     int = 192000 // The highest sample rate a synth will run at.
+)
+
+// Type Settings holds the options that a synth is run with.
+type Settings struct {
+	SampleRate int         // The number of frames per second.
+	BufferSize int         // The number of frames per buffer, or 0 to let the sink decide.
+	MaxVoices  int         // The most voices to play at once, or 0 for no limit.
+	Policy     StealPolicy // How to pick a voice to cut off when over MaxVoices.
+	Gain       float32     // The master gain.
+}
+
+// Creating the default set of Settings.
+func DefaultSettings() Settings {
+	return Settings{
+		SampleRate: DefaultSampleRate,
+		BufferSize: 0,
+		MaxVoices:  0,
+		Policy:     StealOldest,
+		Gain:       DefaultGain,
+	}
+}
+
+// Checking that a set of Settings can be run with.
+func (s Settings) Validate() error {
+	if s.SampleRate < MinSampleRate || s.SampleRate > MaxSampleRate {
+		return errors.New(fmt.Sprintf("Sample rate must be between %d and %d.", MinSampleRate, MaxSampleRate))
+	} else if s.BufferSize < 0 {
+		return errors.New("Buffer size cannot be negative.")
+	} else if s.MaxVoices < 0 {
+		return errors.New("Maximum voices cannot be negative.")
+	} else if !(s.Gain >= 0) || math.IsInf(float64(s.Gain), 0) {
+		return errors.New("Gain must be a finite number that isn't negative.")
+	}
+
+	return nil
+}
+
+// Applying the voice and gain settings to a PrimaryDriver. The sample rate is
+// handed to the driver by whatever steps it.
+func (pd *PrimaryDriver) ApplySettings(settings Settings) {
+	pd.SetPolyphony(settings.MaxVoices, settings.Policy)
+	pd.Bus.SetGain(settings.Gain)
+}
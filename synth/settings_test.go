@@ -0,0 +1,29 @@
+package synth
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSettingsGain(t *testing.T) {
+	tests := []struct {
+		gain  float32
+		valid bool
+	}{
+		{0, true},
+		{DefaultGain, true},
+		{4, true},
+		{-1, false},
+		{float32(math.NaN()), false},
+		{float32(math.Inf(1)), false},
+	}
+
+	for _, test := range tests {
+		settings := DefaultSettings()
+		settings.Gain = test.gain
+
+		if err := settings.Validate(); (err == nil) != test.valid {
+			t.Errorf("Gain %v: expected valid to be %v, got the error %v.", test.gain, test.valid, err)
+		}
+	}
+}
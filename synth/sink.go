@@ -0,0 +1,170 @@
+package synth
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/HardWareGuy/portaudio-go"
+	"github.com/crockeo/go-tuner/filestore/wav"
+	"math"
+	"os"
+	"time"
+)
+
+// The number of frames a push-based sink renders between checks for an exit
+// signal, when the settings leave the buffer size up to the sink.
+const defaultBlockSize int = 512
+
+// Type Sink is an interface to define somewhere that the output of a Driver
+// can be sent.
+type Sink interface {
+	// Running a driver into the sink with a given set of settings until a
+	// signal arrives on exitChannel, or until the driver finishes if
+	// quitWhenDone is set.
+	Run(driver Driver, settings Settings, errChannel chan error, quitWhenDone bool, exitChannel chan bool)
+}
+
+// Running a driver through a function that consumes one frame at a time, and
+// an optional function to flush after every block. The output is paced in real
+// time so that the sink behaves like a sound card would to anything feeding
+// notes into the driver.
+func runBlocks(driver Driver, settings Settings, errChannel chan error, quitWhenDone bool, exitChannel chan bool, write func([]float32) error, flush func() error) {
+	start := time.Now()
+	var frames int64
+
+	blockSize := settings.BufferSize
+	if blockSize == 0 {
+		blockSize = defaultBlockSize
+	}
+
+	// Reporting an error and waiting to be told to exit.
+	fail := func(err error) {
+		errChannel <- err
+		<-exitChannel
+	}
+
+	for {
+		select {
+		case <-exitChannel:
+			return
+		default:
+		}
+
+		done := false
+		for i := 0; i < blockSize && !done; i++ {
+			if err := write(driver.CalculateOutput()); err != nil {
+				fail(err)
+				return
+			}
+
+			driver.StepPhases(settings.SampleRate)
+			frames++
+
+			done = quitWhenDone && driver.Finished()
+		}
+
+		if flush != nil {
+			if err := flush(); err != nil {
+				fail(err)
+				return
+			}
+		}
+
+		if done {
+			exitChannel <- true
+			return
+		}
+
+		elapsed := time.Duration(frames) * time.Second / time.Duration(settings.SampleRate)
+		time.Sleep(elapsed - time.Since(start))
+	}
+}
+
+// A sink that plays audio through the default PortAudio output device.
+type PortAudioSink struct{}
+
+// Running a driver through PortAudio.
+func (s PortAudioSink) Run(driver Driver, settings Settings, errChannel chan error, quitWhenDone bool, exitChannel chan bool) {
+	err := portaudio.Initialize()
+	if err != nil {
+		errChannel <- err
+		<-exitChannel
+		return
+	}
+	defer portaudio.Terminate()
+
+	stream, err := portaudio.OpenDefaultStream(0, driver.OutputChannels(), float64(settings.SampleRate), settings.BufferSize, DriverFunction(driver, settings.SampleRate, quitWhenDone, exitChannel))
+	if err != nil {
+		errChannel <- err
+		<-exitChannel
+		return
+	}
+	defer stream.Close()
+
+	stream.Start()
+	defer stream.Stop()
+
+	<-exitChannel
+}
+
+// A sink that discards its audio. Useful for running headless.
+type NullSink struct{}
+
+// Running a driver and throwing away whatever it outputs.
+func (s NullSink) Run(driver Driver, settings Settings, errChannel chan error, quitWhenDone bool, exitChannel chan bool) {
+	runBlocks(driver, settings, errChannel, quitWhenDone, exitChannel, func([]float32) error { return nil }, nil)
+}
+
+// A sink that records its audio to a WAV file on disk.
+type FileSink struct {
+	Path     string       // The location of the WAV file to write.
+	Encoding wav.Encoding // The sample encoding of the WAV file.
+}
+
+// Running a driver and writing its output to a WAV file.
+func (s FileSink) Run(driver Driver, settings Settings, errChannel chan error, quitWhenDone bool, exitChannel chan bool) {
+	file, err := os.Create(s.Path)
+	if err != nil {
+		errChannel <- err
+		<-exitChannel
+		return
+	}
+	defer file.Close()
+
+	w, err := wav.NewWriter(file, wav.Format{
+		Encoding:   s.Encoding,
+		Channels:   driver.OutputChannels(),
+		SampleRate: settings.SampleRate,
+	})
+	if err != nil {
+		errChannel <- err
+		<-exitChannel
+		return
+	}
+
+	// Flushing after every block keeps the file valid however the sink ends up
+	// being stopped.
+	runBlocks(driver, settings, errChannel, quitWhenDone, exitChannel, w.WriteFrame, w.Flush)
+}
+
+// A sink that writes raw interleaved 16-bit signed little endian PCM to
+// standard output, for piping into something like:
+//
+//	aplay -f S16_LE -c 2 -r <sample rate>
+type StdoutSink struct{}
+
+// Running a driver and writing its output to standard output.
+func (s StdoutSink) Run(driver Driver, settings Settings, errChannel chan error, quitWhenDone bool, exitChannel chan bool) {
+	buffered := bufio.NewWriter(os.Stdout)
+	frame := []int16{}
+
+	runBlocks(driver, settings, errChannel, quitWhenDone, exitChannel,
+		func(output []float32) error {
+			frame = frame[:0]
+			for _, s := range output {
+				frame = append(frame, int16(math.Max(-1, math.Min(1, float64(s)))*math.MaxInt16))
+			}
+
+			return binary.Write(buffered, binary.LittleEndian, frame)
+		},
+		buffered.Flush)
+}
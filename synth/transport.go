@@ -0,0 +1,158 @@
+package synth
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Pausing playback. A paused PrimaryDriver outputs silence and stops moving
+// forward, but keeps every voice where it was.
+func (pd *PrimaryDriver) Pause() {
+	pd.Paused = true
+}
+
+// Resuming playback from wherever it was paused.
+func (pd *PrimaryDriver) Resume() {
+	pd.Paused = false
+}
+
+// Getting the current position of playback in seconds.
+func (pd *PrimaryDriver) Position() float32 {
+	return pd.Time
+}
+
+// Moving playback to a given time in seconds. Notes that would still be
+// sounding at that time are restarted part way through, so that seeking into
+// the middle of a long note doesn't leave a gap. Without KeepHistory, notes
+// that have already started can't be gone back to.
+func (pd *PrimaryDriver) Seek(seconds float32) {
+	if seconds < 0 {
+		seconds = 0
+	}
+
+	target := int64(math.Floor(float64(seconds)*float64(pd.SampleRate) + 0.5))
+
+	pd.CurrentNotes = pd.CurrentNotes[:0]
+	pd.next = sort.Search(len(pd.ScheduledNotes), func(i int) bool {
+		return pd.ScheduledNotes[i].Start >= target
+	})
+
+	for _, sn := range pd.ScheduledNotes[:pd.next] {
+		length := float64(sn.ND.Duration + sn.ND.Envelope.Tail())
+		if sn.Start+int64(length*float64(pd.SampleRate)) <= target {
+			continue
+		}
+
+		sd := NewSingleDriverChild(sn.ND, sn.Start)
+		sd.Samples = target - sn.Start
+		sd.Time = float32(float64(sd.Samples) / float64(pd.SampleRate))
+
+		pd.startVoice(sd)
+	}
+
+	pd.Sample = target
+	pd.Time = float32(float64(pd.Sample) / float64(pd.SampleRate))
+	atomic.StoreInt64(&pd.position, pd.Sample)
+}
+
+// Running every transport command that's waiting for the PrimaryDriver. Like
+// drainInbox, this never blocks.
+func (pd *PrimaryDriver) drainCommands() {
+	for {
+		select {
+		case command := <-pd.commands:
+			command(pd)
+		default:
+			return
+		}
+	}
+}
+
+// Type Controller is used to control the playback of a PrimaryDriver while a
+// Sink is running it. Every method is safe to call from any goroutine, and
+// commands take effect the next time the driver is stepped.
+type Controller struct {
+	pd         *PrimaryDriver
+	sampleRate int
+	stop       chan bool
+	stopOnce   sync.Once
+	done       chan bool
+}
+
+// Creating a new Controller for a PrimaryDriver being run at a given sample
+// rate.
+func newController(pd *PrimaryDriver, sampleRate int) *Controller {
+	c := new(Controller)
+
+	c.pd = pd
+	c.sampleRate = sampleRate
+	c.stop = make(chan bool)
+	c.done = make(chan bool)
+
+	return c
+}
+
+// Sending a command to the PrimaryDriver, giving up if the synth has already
+// stopped.
+func (c *Controller) send(command func(*PrimaryDriver)) {
+	select {
+	case c.pd.commands <- command:
+	case <-c.done:
+	}
+}
+
+// Pausing playback.
+func (c *Controller) Pause() {
+	c.send((*PrimaryDriver).Pause)
+}
+
+// Resuming playback.
+func (c *Controller) Resume() {
+	c.send((*PrimaryDriver).Resume)
+}
+
+// Moving playback to a given time in seconds.
+func (c *Controller) Seek(seconds float32) {
+	c.send(func(pd *PrimaryDriver) {
+		pd.Seek(seconds)
+	})
+}
+
+// Getting the current position of playback in seconds.
+func (c *Controller) Position() float32 {
+	return float32(float64(atomic.LoadInt64(&c.pd.position)) / float64(c.sampleRate))
+}
+
+// Getting the peak output level over the most recent meter window.
+func (c *Controller) Peak() float32 {
+	return c.pd.Bus.Peak()
+}
+
+// Getting the RMS output level over the most recent meter window.
+func (c *Controller) RMS() float32 {
+	return c.pd.Bus.RMS()
+}
+
+// Setting the master gain, as a multiplier on the mixed voices. Unlike the
+// transport commands, it takes effect from the very next frame.
+func (c *Controller) SetGain(gain float32) {
+	c.pd.Bus.SetGain(gain)
+}
+
+// Stopping the synth and waiting for it to shut down. Stopping a synth more
+// than once does nothing.
+func (c *Controller) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+
+	<-c.done
+}
+
+// Getting a channel that's closed once the synth has stopped, for whatever
+// reason.
+func (c *Controller) Done() <-chan bool {
+	return c.done
+}
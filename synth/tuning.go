@@ -0,0 +1,224 @@
+package synth
+
+import (
+	"errors"
+	"math"
+)
+
+// Type Tuning decides the frequency of every note.
+type Tuning interface {
+	// Getting the frequency of a note, or 0 if the tuning leaves the note
+	// unmapped.
+	Frequency(note int) float32
+}
+
+// Type Scale is a set of pitches that repeats every period, in the same form as
+// a Scala .scl file. Degrees holds the ratio of each degree above the first,
+// which is always 1, and the last degree is the period - usually 2 for an
+// octave.
+type Scale struct {
+	Description string
+	Degrees     []float64
+}
+
+// Checking that a Scale can be tuned with.
+func (s Scale) Validate() error {
+	if len(s.Degrees) == 0 {
+		return errors.New("Scales must have at least one degree.")
+	}
+
+	for _, ratio := range s.Degrees {
+		if ratio <= 0 {
+			return errors.New("Scale degrees must be above 0.")
+		}
+	}
+
+	return nil
+}
+
+// Getting the ratio of a degree of the scale above its first degree, where
+// degrees past the end of the scale carry on into the next period.
+func (s Scale) Ratio(degree int) float64 {
+	size := len(s.Degrees)
+	period, step := floorDiv(degree, size)
+
+	ratio := math.Pow(s.Degrees[size-1], float64(period))
+	if step > 0 {
+		ratio *= s.Degrees[step-1]
+	}
+
+	return ratio
+}
+
+// Creating a Scale that splits the octave into a number of equal steps.
+func EqualScale(divisions int) Scale {
+	s := Scale{Description: "Equal temperament", Degrees: make([]float64, divisions)}
+	for i := range s.Degrees {
+		s.Degrees[i] = math.Exp2(float64(i+1) / float64(divisions))
+	}
+
+	return s
+}
+
+// Creating a Scale from a list of whole number ratios, each given as a
+// numerator and denominator.
+func ratioScale(description string, ratios ...[2]float64) Scale {
+	s := Scale{Description: description, Degrees: make([]float64, len(ratios))}
+	for i, r := range ratios {
+		s.Degrees[i] = r[0] / r[1]
+	}
+
+	return s
+}
+
+// The 5-limit just intonation scale, starting on its tonic.
+var JustScale Scale = ratioScale("5-limit just intonation",
+	[2]float64{16, 15}, [2]float64{9, 8}, [2]float64{6, 5}, [2]float64{5, 4},
+	[2]float64{4, 3}, [2]float64{45, 32}, [2]float64{3, 2}, [2]float64{8, 5},
+	[2]float64{5, 3}, [2]float64{9, 5}, [2]float64{15, 8}, [2]float64{2, 1})
+
+// The Pythagorean scale, built from pure fifths, starting on its tonic.
+var PythagoreanScale Scale = ratioScale("Pythagorean",
+	[2]float64{256, 243}, [2]float64{9, 8}, [2]float64{32, 27}, [2]float64{81, 64},
+	[2]float64{4, 3}, [2]float64{729, 512}, [2]float64{3, 2}, [2]float64{128, 81},
+	[2]float64{27, 16}, [2]float64{16, 9}, [2]float64{243, 128}, [2]float64{2, 1})
+
+// Creating a 12 note meantone Scale from the size of its fifth, running from
+// the flattened third to the sharpened fifth.
+func MeantoneScale(fifth float64) Scale {
+	s := Scale{Description: "Meantone", Degrees: make([]float64, 12)}
+	for fifths := -3; fifths <= 8; fifths++ {
+		ratio := math.Pow(fifth, float64(fifths))
+		ratio /= math.Exp2(math.Floor(math.Log2(ratio)))
+
+		if _, step := floorDiv(fifths*7, 12); step > 0 {
+			s.Degrees[step-1] = ratio
+		}
+	}
+	s.Degrees[11] = 2
+
+	return s
+}
+
+// The fifth of quarter-comma meantone, which makes its major thirds pure.
+var QuarterCommaFifth float64 = math.Pow(5, 0.25)
+
+// Type KeyboardMapping decides which degree of a Scale every note plays, and
+// pins one note to a frequency, in the same form as a Scala .kbm file. Notes
+// use the same numbering as NoteToInt.
+type KeyboardMapping struct {
+	Size         int     // The number of notes before the mapping repeats, or 0 to map every note to the next degree.
+	First        int     // The lowest note that's mapped.
+	Last         int     // The highest note that's mapped.
+	Middle       int     // The note that plays the first degree of the scale.
+	Reference    int     // The note that's given a frequency.
+	Frequency    float64 // The frequency of the reference note.
+	OctaveDegree int     // The degree that each repeat of the mapping moves up by, or 0 for the size of the scale.
+	Keys         []int   // The degree each note plays from Middle onwards, or -1 to leave a note unmapped.
+}
+
+// Creating a KeyboardMapping that plays every degree of a scale in order from
+// a tonic, with A4 at a reference frequency.
+func StandardMapping(tonic int, reference float32) KeyboardMapping {
+	return KeyboardMapping{
+		First:     math.MinInt32,
+		Last:      math.MaxInt32,
+		Middle:    tonic,
+		Reference: A4I,
+		Frequency: float64(reference),
+	}
+}
+
+// Checking that a KeyboardMapping can be tuned with.
+func (km KeyboardMapping) Validate() error {
+	if km.Size < 0 {
+		return errors.New("Keyboard mappings can't have a negative size.")
+	} else if km.Size != len(km.Keys) {
+		return errors.New("Keyboard mappings must have as many keys as their size.")
+	} else if km.Frequency <= 0 {
+		return errors.New("Keyboard mappings must have a reference frequency above 0.")
+	} else if _, ok := km.Degree(km.Reference, 1); !ok {
+		return errors.New("The reference note of a keyboard mapping must be mapped.")
+	}
+
+	return nil
+}
+
+// Getting the degree of a scale of a given size that a note plays, and whether
+// the note is mapped at all.
+func (km KeyboardMapping) Degree(note int, scaleSize int) (int, bool) {
+	if note < km.First || note > km.Last {
+		return 0, false
+	}
+
+	if km.Size == 0 {
+		return note - km.Middle, true
+	}
+
+	octaveDegree := km.OctaveDegree
+	if octaveDegree == 0 {
+		octaveDegree = scaleSize
+	}
+
+	repeat, key := floorDiv(note-km.Middle, km.Size)
+	if km.Keys[key] < 0 {
+		return 0, false
+	}
+
+	return repeat*octaveDegree + km.Keys[key], true
+}
+
+// Type ScaleTuning is a Tuning that plays a Scale through a KeyboardMapping.
+type ScaleTuning struct {
+	Scale   Scale
+	Mapping KeyboardMapping
+}
+
+// Creating a ScaleTuning, checking that its scale and mapping can be used.
+func NewScaleTuning(scale Scale, mapping KeyboardMapping) (ScaleTuning, error) {
+	if err := scale.Validate(); err != nil {
+		return ScaleTuning{}, err
+	}
+
+	if err := mapping.Validate(); err != nil {
+		return ScaleTuning{}, err
+	}
+
+	return ScaleTuning{scale, mapping}, nil
+}
+
+// Getting the frequency of a note.
+func (st ScaleTuning) Frequency(note int) float32 {
+	size := len(st.Scale.Degrees)
+
+	degree, ok := st.Mapping.Degree(note, size)
+	if !ok {
+		return 0
+	}
+
+	reference, _ := st.Mapping.Degree(st.Mapping.Reference, size)
+
+	return float32(st.Mapping.Frequency * st.Scale.Ratio(degree) / st.Scale.Ratio(reference))
+}
+
+// Creating a 12 tone equal temperament Tuning with A4 at a reference
+// frequency, such as 440, 442 or 415.
+func EqualTemperament(reference float32) Tuning {
+	return ScaleTuning{EqualScale(12), StandardMapping(0, reference)}
+}
+
+// The Tuning used when none is given - 12 tone equal temperament with A4 at
+// 440Hz.
+var StandardTuning Tuning = EqualTemperament(A4)
+
+// Dividing two numbers, rounding towards negative infinity, and getting the
+// remainder that goes with it.
+func floorDiv(a, b int) (int, int) {
+	q, r := a/b, a%b
+	if r < 0 {
+		q--
+		r += b
+	}
+
+	return q, r
+}
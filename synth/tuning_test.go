@@ -0,0 +1,98 @@
+package synth
+
+import (
+	"math"
+	"testing"
+)
+
+// Converting a ratio to cents.
+func ratioCents(ratio float64) float64 {
+	return 1200 * math.Log2(ratio)
+}
+
+// Checking that every degree of a scale is within a hundredth of a cent of a
+// list of pitches in cents.
+func checkScaleCents(t *testing.T, scale Scale, cents []float64) {
+	if len(scale.Degrees) != len(cents) {
+		t.Fatalf("%s: expected %d degrees, got %d.", scale.Description, len(cents), len(scale.Degrees))
+	}
+
+	for i, ratio := range scale.Degrees {
+		if got := ratioCents(ratio); math.Abs(got-cents[i]) > 0.01 {
+			t.Errorf("%s: expected degree %d at %.2f cents, got %.2f.", scale.Description, i+1, cents[i], got)
+		}
+	}
+}
+
+func TestJustScale(t *testing.T) {
+	checkScaleCents(t, JustScale, []float64{
+		111.73, 203.91, 315.64, 386.31, 498.04, 590.22,
+		701.96, 813.69, 884.36, 1017.60, 1088.27, 1200,
+	})
+}
+
+func TestPythagoreanScale(t *testing.T) {
+	checkScaleCents(t, PythagoreanScale, []float64{
+		90.22, 203.91, 294.13, 407.82, 498.04, 611.73,
+		701.96, 792.18, 905.87, 996.09, 1109.78, 1200,
+	})
+}
+
+func TestMeantoneScale(t *testing.T) {
+	// Quarter-comma meantone, from Eb up to G# in fifths.
+	checkScaleCents(t, MeantoneScale(QuarterCommaFifth), []float64{
+		76.05, 193.16, 310.26, 386.31, 503.42, 579.47,
+		696.58, 772.63, 889.74, 1006.84, 1082.89, 1200,
+	})
+
+	// An equal tempered fifth gives back equal temperament.
+	checkScaleCents(t, MeantoneScale(math.Exp2(7.0/12.0)), []float64{
+		100, 200, 300, 400, 500, 600, 700, 800, 900, 1000, 1100, 1200,
+	})
+}
+
+func TestScaleTuningFrequencies(t *testing.T) {
+	tests := []struct {
+		scale  Scale
+		tonic  string
+		note   string
+		expect float64
+	}{
+		// Tuned from C4 with A4 at 440Hz, so C4 is 440 / (5/3) = 264Hz.
+		{JustScale, "C4", "C4", 264},
+		{JustScale, "C4", "E4", 330},
+		{JustScale, "C4", "G4", 396},
+		{JustScale, "C4", "C3", 132},
+		{JustScale, "C4", "B3", 247.5},
+
+		// C4 is 440 / (27/16) = 260.74Hz.
+		{PythagoreanScale, "C4", "C4", 260.740741},
+		{PythagoreanScale, "C4", "E4", 330},
+		{PythagoreanScale, "C4", "G4", 391.111111},
+
+		// Tuned from A, so A4 keeps its 440Hz and E5 is a pure fifth above.
+		{JustScale, "A4", "E5", 660},
+		{JustScale, "A4", "C#5", 550},
+	}
+
+	for _, test := range tests {
+		tonic, err := NoteToInt(test.tonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		note, err := NoteToInt(test.note)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tuning, err := NewScaleTuning(test.scale, StandardMapping(tonic, 440))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f := float64(tuning.Frequency(note)); math.Abs(f-test.expect) > 1e-3 {
+			t.Errorf("%s from %s: expected %s at %vHz, got %vHz.", test.scale.Description, test.tonic, test.note, test.expect, f)
+		}
+	}
+}
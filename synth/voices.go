@@ -0,0 +1,108 @@
+package synth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/crockeo/go-tuner/config"
+)
+
+// Type StealPolicy decides which voice is cut off to make room for a new note
+// when a PrimaryDriver is already playing as many voices as it's allowed.
+type StealPolicy int
+
+const (
+	StealOldest   StealPolicy = iota // Cutting off the voice that started first.
+	StealQuietest                    // Cutting off the voice that is currently quietest.
+)
+
+// The names each StealPolicy can be chosen by.
+var stealPolicies = map[string]StealPolicy{
+	"oldest":   StealOldest,
+	"quietest": StealQuietest,
+}
+
+// Parsing a StealPolicy from its name, either "oldest" or "quietest".
+func ParseStealPolicy(name string) (StealPolicy, error) {
+	policy, ok := stealPolicies[name]
+	if !ok {
+		return StealOldest, errors.New("Unknown voice stealing policy: " + name)
+	}
+
+	return policy, nil
+}
+
+// Getting how loud a voice is at the moment, ignoring the phases of its
+// overtones.
+func (sd *SingleDriver) Loudness() float32 {
+	return sd.Note.Volume * sd.Note.Envelope.Level(sd.Time, sd.Note.Duration)
+}
+
+// Setting the maximum number of voices a PrimaryDriver plays at once, and how
+// it picks a voice to cut off when a note would go over it. A maximum of 0
+// means there is no limit.
+func (pd *PrimaryDriver) SetPolyphony(maxVoices int, policy StealPolicy) {
+	pd.MaxVoices = maxVoices
+	pd.Policy = policy
+
+	for pd.MaxVoices > 0 && len(pd.CurrentNotes) > pd.MaxVoices {
+		pd.stealVoice()
+	}
+}
+
+// Removing every voice that has finished playing, wherever it is in the list
+// of current notes.
+func (pd *PrimaryDriver) dropFinished() {
+	live := pd.CurrentNotes[:0]
+	for _, sd := range pd.CurrentNotes {
+		if !sd.Finished() {
+			live = append(live, sd)
+		}
+	}
+
+	// Clearing out the rest of the backing array so that the finished voices
+	// can be collected.
+	for i := len(live); i < len(pd.CurrentNotes); i++ {
+		pd.CurrentNotes[i] = nil
+	}
+
+	pd.CurrentNotes = live
+}
+
+// Removing a single voice chosen by the PrimaryDriver's StealPolicy.
+func (pd *PrimaryDriver) stealVoice() {
+	if len(pd.CurrentNotes) == 0 {
+		return
+	}
+
+	// Current notes are kept in the order they started, so the oldest voice
+	// is always the first.
+	steal := 0
+	if pd.Policy == StealQuietest {
+		quietest := pd.CurrentNotes[0].Loudness()
+		for i, sd := range pd.CurrentNotes[1:] {
+			if l := sd.Loudness(); l < quietest {
+				steal = i + 1
+				quietest = l
+			}
+		}
+	}
+
+	if config.DebugMode {
+		fmt.Print("Stealing voice: ")
+		fmt.Println(pd.CurrentNotes[steal].Note)
+	}
+
+	copy(pd.CurrentNotes[steal:], pd.CurrentNotes[steal+1:])
+	pd.CurrentNotes[len(pd.CurrentNotes)-1] = nil
+	pd.CurrentNotes = pd.CurrentNotes[:len(pd.CurrentNotes)-1]
+}
+
+// Starting a new voice, stealing another first if the PrimaryDriver is already
+// playing as many as it's allowed.
+func (pd *PrimaryDriver) startVoice(sd *SingleDriver) {
+	for pd.MaxVoices > 0 && len(pd.CurrentNotes) >= pd.MaxVoices {
+		pd.stealVoice()
+	}
+
+	pd.CurrentNotes = append(pd.CurrentNotes, sd)
+}
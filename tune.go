@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"github.com/crockeo/go-tuner/filestore/wav"
+	"github.com/crockeo/go-tuner/synth"
+	"github.com/crockeo/go-tuner/tuner"
+	"io"
+	"os"
+	"os/signal"
+)
+
+// Printing the pitch of a WAV file over time.
+func tuneFile(path string, tuning synth.Tuning) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, err := wav.NewReader(file)
+	if err != nil {
+		return err
+	}
+
+	rate := reader.Format().SampleRate
+	t := tuner.NewTuner(rate)
+	t.Tuning = tuning
+
+	for frames := 0; ; frames++ {
+		frame, err := reader.ReadFrame()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if reading, ok := t.AddFrame(frame); ok {
+			fmt.Printf("%8.2fs  %s\n", float64(frames)/float64(rate), reading)
+		}
+	}
+}
+
+// Printing the pitch heard on the default input device until interrupted.
+func tuneInput(settings synth.Settings, tuning synth.Tuning) error {
+	t := tuner.NewTuner(settings.SampleRate)
+	t.Tuning = tuning
+
+	readings := make(chan tuner.Reading, 8)
+	quitChannel := make(chan bool)
+	errChannel := make(chan error, 1)
+
+	go func() {
+		errChannel <- tuner.Listen(t, settings.BufferSize, readings, quitChannel)
+	}()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	for {
+		select {
+		case reading := <-readings:
+			// Writing over the same line, so the reading stays in one place
+			// like on a hardware tuner.
+			fmt.Printf("\r%-32s", reading)
+		case _ = <-interrupts:
+			fmt.Println()
+			quitChannel <- true
+			return <-errChannel
+		case err := <-errChannel:
+			return err
+		}
+	}
+}
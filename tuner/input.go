@@ -0,0 +1,67 @@
+package tuner
+
+import (
+	"github.com/HardWareGuy/portaudio-go"
+)
+
+// The number of input buffers that can wait to be analysed before new ones
+// are dropped.
+const inputQueue int = 16
+
+// Listening to the default PortAudio input device, sending a Reading through
+// oReadingChannel whenever the Tuner makes one, until a signal arrives on
+// iQuitChannel.
+//
+// bufferSize is the number of frames per input buffer, or 0 to let PortAudio
+// decide.
+func Listen(t *Tuner, bufferSize int, oReadingChannel chan Reading, iQuitChannel chan bool) error {
+	if err := portaudio.Initialize(); err != nil {
+		return err
+	}
+	defer portaudio.Terminate()
+
+	// Analysing is too slow to do inside of the audio callback, so buffers are
+	// copied out and analysed here instead. If the analysis falls behind,
+	// buffers are dropped rather than holding up the callback.
+	buffers := make(chan []float32, inputQueue)
+	stream, err := portaudio.OpenDefaultStream(1, 0, float64(t.SampleRate), bufferSize, func(in []float32) {
+		buffer := make([]float32, len(in))
+		copy(buffer, in)
+
+		select {
+		case buffers <- buffer:
+		default:
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	if err := stream.Start(); err != nil {
+		return err
+	}
+	defer stream.Stop()
+
+	frame := make([]float32, 1)
+	for {
+		select {
+		case _ = <-iQuitChannel:
+			return nil
+		case buffer := <-buffers:
+			for _, s := range buffer {
+				frame[0] = s
+				reading, ok := t.AddFrame(frame)
+				if !ok {
+					continue
+				}
+
+				select {
+				case oReadingChannel <- reading:
+				case _ = <-iQuitChannel:
+					return nil
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package tuner
+
+import (
+	"fmt"
+	"github.com/crockeo/go-tuner/synth"
+	"math"
+)
+
+const (
+	DefaultMinFrequency float32 = 30   // The lowest frequency listened for, just under B0.
+	DefaultMaxFrequency float32 = 4200 // The highest frequency listened for, just over C8.
+	DefaultThreshold    float32 = 0.15 // The YIN threshold used when none is given.
+	DefaultSilence      float32 = 0.01 // The RMS level under which a window counts as silence.
+)
+
+// Type Reading is a single estimate of the pitch being played.
+type Reading struct {
+	Frequency float32 // The estimated fundamental frequency.
+	Note      int     // The closest note in the tuning.
+	Cents     float32 // How far the frequency is from the note, where positive is sharp.
+	Clarity   float32 // How clearly periodic the sound was, from 0 to 1.
+}
+
+// Converting a Reading to a string, like "A4 +3.2c (441.23Hz)".
+func (r Reading) String() string {
+	return fmt.Sprintf("%s %+.1fc (%.2fHz)", synth.NoteToString(r.Note), r.Cents, r.Frequency)
+}
+
+// Finding the note closest to a frequency in a tuning, and how many cents the
+// frequency is away from it. Notes the tuning leaves unmapped are skipped.
+func Nearest(tuning synth.Tuning, frequency float32) (int, float32) {
+	// Starting from the closest equal tempered note, and then looking at the
+	// notes around it, which covers tunings that stray from equal temperament.
+	guess := synth.A4I + int(math.Floor(12*math.Log2(float64(frequency/synth.A4))+0.5))
+
+	note := guess
+	var cents float64 = math.Inf(1)
+	for n := guess - 2; n <= guess+2; n++ {
+		f := tuning.Frequency(n)
+		if f <= 0 {
+			continue
+		}
+
+		c := 1200 * math.Log2(float64(frequency/f))
+		if math.Abs(c) < math.Abs(cents) {
+			note, cents = n, c
+		}
+	}
+
+	if math.IsInf(cents, 0) {
+		return guess, 0
+	}
+
+	return note, float32(cents)
+}
+
+// Type Tuner takes frames of audio as they arrive and turns them into
+// Readings at regular intervals.
+type Tuner struct {
+	SampleRate   int          // The sample rate of the frames being given.
+	WindowSize   int          // The number of samples looked at for each Reading.
+	HopSize      int          // The number of samples between Readings.
+	MinFrequency float32      // The lowest frequency listened for.
+	MaxFrequency float32      // The highest frequency listened for.
+	Threshold    float32      // The YIN threshold.
+	Silence      float32      // The RMS level under which no Reading is made.
+	Overlap      float32      // The YIN overlap, for listening to notes played over others, or 0 for none.
+	Tuning       synth.Tuning // The tuning to find the closest notes in.
+
+	window []float32 // The most recent samples, mixed down to mono.
+	since  int       // The number of samples since the last Reading.
+}
+
+// Creating a new Tuner for a given sample rate, tuned to the StandardTuning
+// and making 20 Readings a second.
+func NewTuner(sampleRate int) *Tuner {
+	t := new(Tuner)
+
+	t.SampleRate = sampleRate
+	t.MinFrequency = DefaultMinFrequency
+	t.MaxFrequency = DefaultMaxFrequency
+	t.Threshold = DefaultThreshold
+	t.Silence = DefaultSilence
+	t.Tuning = synth.StandardTuning
+
+	// Leaving room for two of the longest periods listened for.
+	t.WindowSize = 2 * (int(float32(sampleRate)/t.MinFrequency) + 1)
+	t.HopSize = sampleRate / 20
+
+	return t
+}
+
+// Estimating the pitch of a window of mono samples, if there is one.
+func (t *Tuner) Analyse(samples []float32) (Reading, bool) {
+	var power float32
+	for _, s := range samples {
+		power += s * s
+	}
+
+	if len(samples) == 0 || float32(math.Sqrt(float64(power/float32(len(samples))))) < t.Silence {
+		return Reading{}, false
+	}
+
+	frequency, clarity, ok := YIN(samples, t.SampleRate, t.MinFrequency, t.MaxFrequency, t.Threshold, t.Overlap)
+	if !ok {
+		return Reading{}, false
+	}
+
+	note, cents := Nearest(t.Tuning, frequency)
+
+	return Reading{
+		Frequency: frequency,
+		Note:      note,
+		Cents:     cents,
+		Clarity:   clarity,
+	}, true
+}
+
+// Adding a frame of samples, one per channel, which are mixed down to mono.
+// Returns a Reading once every HopSize frames, whenever there's a pitch to be
+// heard.
+func (t *Tuner) AddFrame(frame []float32) (Reading, bool) {
+	var sample float32
+	for _, s := range frame {
+		sample += s
+	}
+	if len(frame) > 0 {
+		sample /= float32(len(frame))
+	}
+
+	if cap(t.window) < 2*t.WindowSize {
+		window := make([]float32, len(t.window), 2*t.WindowSize)
+		copy(window, t.window)
+		t.window = window
+	}
+
+	// Moving the window back to the start of its buffer only once the buffer
+	// fills up, rather than on every sample.
+	if len(t.window) == cap(t.window) {
+		t.window = append(t.window[:0], t.window[len(t.window)-t.WindowSize+1:]...)
+	}
+	t.window = append(t.window, sample)
+	t.since++
+
+	if len(t.window) < t.WindowSize || t.since < t.HopSize {
+		return Reading{}, false
+	}
+	t.since = 0
+
+	return t.Analyse(t.window[len(t.window)-t.WindowSize:])
+}
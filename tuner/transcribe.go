@@ -0,0 +1,263 @@
+package tuner
+
+import (
+	"github.com/crockeo/go-tuner/synth"
+	"math"
+)
+
+const (
+	energyRate     int     = 200 // The number of energy measurements made a second.
+	pitchEvery     int     = 4   // The number of energy measurements between pitch measurements.
+	riseWindow     int     = 6   // The number of energy measurements an onset has to rise within.
+	minChangeRun   int     = 3   // The number of pitch measurements a new note has to hold for to split a note.
+	attackSkip     int     = 2   // The number of pitch measurements ignored at the start of a note.
+	floorDecibels  float64 = -60 // The level under which everything is silence.
+	rangeDecibels  float64 = 40  // How far under the loudest point a recording counts as silent.
+	decayDecibels  float64 = 24  // How far a note has to fall under its peak to count as over.
+	ringingOverlap float32 = 0.4 // The YIN overlap, since earlier notes are usually still ringing.
+)
+
+// The quietest volume a note is given, about 26dB under the loudest one. Notes
+// are heard as far as rangeDecibels under it, and played back that quietly
+// they'd be lost under the notes around them.
+const quietestVolume float32 = 0.05
+
+// Type Note is a single note heard in a recording.
+type Note struct {
+	Start    float32 // The time the note starts, in seconds.
+	Duration float32 // How long the note sounds for, in seconds.
+	Note     int     // The note, in the numbering used by synth.NoteToInt.
+	Volume   float32 // How loud the note is next to the loudest note, from 0 to 1.
+}
+
+// Type Transcriber turns a recording of a melody into a list of Notes, finding
+// where notes start from sudden rises in loudness and changes in pitch, and
+// what they are with YIN. Only the strongest pitch is followed at any time, so
+// chords come out as a single note.
+type Transcriber struct {
+	SampleRate int          // The sample rate of the recording.
+	OnsetRise  float64      // How many decibels loudness has to jump by for a note to start.
+	MinLength  float32      // The length in seconds under which notes are dropped.
+	Tuning     synth.Tuning // The tuning to find the closest notes in.
+
+	tuner *Tuner
+}
+
+// Creating a new Transcriber for recordings at a given sample rate.
+func NewTranscriber(sampleRate int) *Transcriber {
+	t := new(Transcriber)
+
+	t.SampleRate = sampleRate
+	t.OnsetRise = 6
+	t.MinLength = 0.04
+	t.Tuning = synth.StandardTuning
+
+	// Listening from A1 upwards keeps the window short enough to measure the
+	// pitch often.
+	t.tuner = NewTuner(sampleRate)
+	t.tuner.MinFrequency = 55
+	t.tuner.WindowSize = 2 * (int(float32(sampleRate)/t.tuner.MinFrequency) + 1)
+
+	// Silence is found from the loudness of the whole recording instead.
+	t.tuner.Silence = 0
+	t.tuner.Overlap = ringingOverlap
+
+	return t
+}
+
+// Type segment is a run of energy measurements that one note is played over.
+type segment struct {
+	start, end int
+}
+
+// Measuring how loud every short slice of a recording is, in decibels.
+func (t *Transcriber) energy(samples []float32, hop int) []float64 {
+	levels := make([]float64, len(samples)/hop)
+	for i := range levels {
+		end := i*hop + 2*hop
+		if end > len(samples) {
+			end = len(samples)
+		}
+
+		var power float64
+		for _, s := range samples[i*hop : end] {
+			power += float64(s) * float64(s)
+		}
+
+		levels[i] = 10 * math.Log10(power/float64(end-i*hop)+1e-12)
+	}
+
+	return levels
+}
+
+// Splitting a recording into the runs where notes are sounding, starting a new
+// run at every onset.
+func (t *Transcriber) segment(levels []float64) []segment {
+	loudest := floorDecibels
+	for _, l := range levels {
+		loudest = math.Max(loudest, l)
+	}
+	silence := math.Max(floorDecibels, loudest-rangeDecibels)
+
+	segments := []segment{}
+	current := -1
+	peak := 0.0
+	rising := false
+
+	for i, l := range levels {
+		// Onsets fire on the measurement where loudness first jumps, and not
+		// again until it's stopped rising.
+		quietest := l
+		for j := i - riseWindow; j < i; j++ {
+			if j >= 0 {
+				quietest = math.Min(quietest, levels[j])
+			}
+		}
+
+		onset := l > silence && l-quietest >= t.OnsetRise && !rising
+		rising = l-quietest >= t.OnsetRise
+
+		if current >= 0 && (onset || l < silence || l < peak-decayDecibels) {
+			segments = append(segments, segment{current, i})
+			current = -1
+		}
+
+		if current < 0 && l > silence && (onset || i == 0 || levels[i-1] < silence) {
+			current = i
+			peak = l
+		}
+
+		peak = math.Max(peak, l)
+	}
+
+	if current >= 0 {
+		segments = append(segments, segment{current, len(levels)})
+	}
+
+	return segments
+}
+
+// Finding the most common note in a run of pitch measurements, ignoring
+// measurements without a pitch.
+func mostCommon(notes []int, ok []bool) (int, bool) {
+	counts := map[int]int{}
+	best, found := 0, false
+
+	for i, n := range notes {
+		if !ok[i] {
+			continue
+		}
+
+		counts[n]++
+		if !found || counts[n] > counts[best] {
+			best, found = n, true
+		}
+	}
+
+	return best, found
+}
+
+// Transcribing a mono recording into a list of Notes in the order they start.
+func (t *Transcriber) Transcribe(samples []float32) []Note {
+	t.tuner.Tuning = t.Tuning
+
+	hop := t.SampleRate / energyRate
+	if hop < 1 {
+		hop = 1
+	}
+
+	levels := t.energy(samples, hop)
+
+	// Measuring the pitch every few energy measurements, over a window
+	// starting at the same point.
+	count := (len(levels) + pitchEvery - 1) / pitchEvery
+	notes := make([]int, count)
+	pitched := make([]bool, count)
+	for i := range notes {
+		start := i * pitchEvery * hop
+		end := start + t.tuner.WindowSize
+		if end > len(samples) {
+			continue
+		}
+
+		if reading, ok := t.tuner.Analyse(samples[start:end]); ok {
+			notes[i], pitched[i] = reading.Note, true
+		}
+	}
+
+	// Splitting runs wherever the pitch changes to a new note and holds it,
+	// for notes that change without a jump in loudness.
+	split := []segment{}
+	for _, s := range t.segment(levels) {
+		from, first := s.start/pitchEvery, s.start/pitchEvery+attackSkip
+		to := min(first+minChangeRun, count)
+		current, _ := mostCommon(notes[from:to], pitched[from:to])
+
+		for p := first; p+minChangeRun <= count && (p+minChangeRun)*pitchEvery <= s.end; p++ {
+			held := true
+			for q := p; q < p+minChangeRun; q++ {
+				held = held && pitched[q] && notes[q] != current && notes[q] == notes[p]
+			}
+
+			if !held {
+				continue
+			}
+
+			// A change this close to the start is still part of the attack,
+			// rather than a note of its own.
+			if float32((p*pitchEvery-s.start)*hop) >= 2*t.MinLength*float32(t.SampleRate) {
+				split = append(split, segment{s.start, p * pitchEvery})
+				s.start = p * pitchEvery
+			}
+			current = notes[p]
+		}
+
+		split = append(split, s)
+	}
+
+	// Naming each note from the pitch measurements made during it, and
+	// measuring how loud it is.
+	result := []Note{}
+	var loudest float64 = floorDecibels
+	peaks := []float64{}
+	for _, s := range split {
+		length := float32(s.end-s.start) * float32(hop) / float32(t.SampleRate)
+		if length < t.MinLength {
+			continue
+		}
+
+		from, to := s.start/pitchEvery, (s.end+pitchEvery-1)/pitchEvery
+		if to-from > attackSkip+1 {
+			from += attackSkip
+		}
+
+		note, ok := mostCommon(notes[from:min(to, count)], pitched[from:min(to, count)])
+		if !ok {
+			continue
+		}
+
+		peak := floorDecibels
+		for _, l := range levels[s.start:s.end] {
+			peak = math.Max(peak, l)
+		}
+		loudest = math.Max(loudest, peak)
+		peaks = append(peaks, peak)
+
+		result = append(result, Note{
+			Start:    float32(s.start*hop) / float32(t.SampleRate),
+			Duration: length,
+			Note:     note,
+		})
+	}
+
+	for i := range result {
+		volume := float32(math.Pow(10, (peaks[i]-loudest)/20))
+		if volume < quietestVolume {
+			volume = quietestVolume
+		}
+
+		result[i].Volume = volume
+	}
+
+	return result
+}
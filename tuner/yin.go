@@ -0,0 +1,124 @@
+package tuner
+
+// Estimating the fundamental frequency of a window of samples with the YIN
+// algorithm, looking for periods between minFrequency and maxFrequency. The
+// threshold is how aperiodic a period can be and still be picked, where 0.1 to
+// 0.2 suit most instruments. The overlap is how much less periodic than the
+// picked period a fraction of it can be and still be picked in its place, for
+// recordings where earlier notes are still ringing, or 0 to never do so.
+// Returns the frequency, how clearly periodic the window was from 0 to 1, and
+// whether a period was found at all.
+func YIN(samples []float32, sampleRate int, minFrequency, maxFrequency, threshold, overlap float32) (float32, float32, bool) {
+	minTau := int(float32(sampleRate) / maxFrequency)
+	maxTau := int(float32(sampleRate)/minFrequency) + 1
+	if minTau < 2 {
+		minTau = 2
+	}
+
+	// The window being compared has to leave room for the longest period.
+	width := len(samples) - maxTau
+	if width <= 0 || minTau >= maxTau {
+		return 0, 0, false
+	}
+
+	// Finding the difference between the window and itself shifted by every
+	// period, normalised by the running mean so that short periods aren't
+	// favoured.
+	raw := make([]float32, maxTau+1)
+	diff := make([]float32, maxTau+1)
+	diff[0] = 1
+
+	var total float32
+	for tau := 1; tau <= maxTau; tau++ {
+		var d float32
+		for j := 0; j < width; j++ {
+			delta := samples[j] - samples[j+tau]
+			d += delta * delta
+		}
+
+		raw[tau] = d
+		total += d
+		if total == 0 {
+			diff[tau] = 1
+		} else {
+			diff[tau] = d * float32(tau) / total
+		}
+	}
+
+	// Finding the deepest dip, which is noise rather than a note when even it
+	// is this aperiodic.
+	deepest := minTau
+	for tau := minTau; tau < maxTau; tau++ {
+		if diff[tau] < diff[deepest] {
+			deepest = tau
+		}
+	}
+
+	if diff[deepest] >= 0.5 {
+		return 0, 1 - diff[deepest], false
+	}
+
+	// Picking the first dip below the threshold, following it down to the
+	// bottom. Every multiple of a period dips too, and with other notes still
+	// ringing a multiple can be the deepest, so when nothing is below the
+	// threshold the first dip within it of the deepest is picked instead.
+	limit := threshold
+	if diff[deepest] >= threshold {
+		limit = diff[deepest] + threshold
+	}
+
+	best := deepest
+	for tau := minTau; tau < deepest; tau++ {
+		if diff[tau] < limit {
+			for tau+1 < maxTau && diff[tau+1] < diff[tau] {
+				tau++
+			}
+
+			best = tau
+			break
+		}
+	}
+
+	// A note played over others that are still ringing can repeat at a
+	// multiple of its own period, which they all share, so fractions of the
+	// period are looked at too. The shortest one that dips close enough to it
+	// is taken.
+	if overlap > 0 {
+		for k := best / minTau; k >= 2; k-- {
+			if tau, ok := dipNear(diff, best/k, minTau); ok && diff[tau] < diff[best]+overlap {
+				best = tau
+				break
+			}
+		}
+	}
+
+	// Fitting a parabola through the dip to find the period between samples.
+	// The raw difference is used, since normalising it skews the dip.
+	period := float32(best)
+	if best > 1 && best < maxTau {
+		a, b, c := raw[best-1], raw[best], raw[best+1]
+		if denom := a - 2*b + c; denom != 0 {
+			period += (a - c) / (2 * denom)
+		}
+	}
+
+	return float32(sampleRate) / period, 1 - diff[best], true
+}
+
+// Finding the bottom of a dip in the normalised difference close to a period,
+// if there is one.
+func dipNear(diff []float32, tau int, minTau int) (int, bool) {
+	from, to := tau-tau/20-1, tau+tau/20+1
+	if from < minTau {
+		from = minTau
+	}
+
+	bottom := from
+	for t := from; t <= to; t++ {
+		if diff[t] < diff[bottom] {
+			bottom = t
+		}
+	}
+
+	return bottom, bottom > from && bottom < to
+}
@@ -0,0 +1,99 @@
+package tuner
+
+import (
+	"github.com/crockeo/go-tuner/synth"
+	"math"
+	"testing"
+)
+
+// The sample rate that test signals are made at.
+const testRate int = 44100
+
+// Making a second of a sine wave at a frequency, with some harmonics added
+// when asked for.
+func makeSine(frequency float64, harmonics bool) []float32 {
+	samples := make([]float32, testRate)
+	for i := range samples {
+		phase := 2 * math.Pi * frequency * float64(i) / float64(testRate)
+
+		s := 0.5 * math.Sin(phase)
+		if harmonics {
+			s += 0.25*math.Sin(2*phase) + 0.15*math.Sin(3*phase)
+		}
+
+		samples[i] = float32(s)
+	}
+
+	return samples
+}
+
+func TestYIN(t *testing.T) {
+	tests := []struct {
+		frequency float64
+		note      string
+		cents     float32
+	}{
+		{82.41, "E2", 0.04},
+		{440, "A4", 0},
+		{440 * math.Exp2(15.0/1200.0), "A4", 15},
+		{440 * math.Exp2(-40.0/1200.0), "A4", -40},
+		{261.63, "C4", 0.01},
+	}
+
+	for _, test := range tests {
+		note, err := synth.NoteToInt(test.note)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, harmonics := range []bool{false, true} {
+			tuner := NewTuner(testRate)
+
+			reading, ok := tuner.Analyse(makeSine(test.frequency, harmonics)[:tuner.WindowSize])
+			if !ok {
+				t.Errorf("%vHz: expected a reading.", test.frequency)
+				continue
+			}
+
+			if math.Abs(float64(reading.Frequency)-test.frequency) > test.frequency*0.001 {
+				t.Errorf("%vHz: expected the frequency to be found, got %vHz.", test.frequency, reading.Frequency)
+			}
+
+			if reading.Note != note || math.Abs(float64(reading.Cents-test.cents)) > 1 {
+				t.Errorf("%vHz: expected %s %+.1fc, got %s.", test.frequency, test.note, test.cents, reading)
+			}
+		}
+	}
+}
+
+func TestTunerReadings(t *testing.T) {
+	tuner := NewTuner(testRate)
+
+	readings := 0
+	for _, s := range makeSine(440*math.Exp2(15.0/1200.0), true) {
+		reading, ok := tuner.AddFrame([]float32{s, s})
+		if !ok {
+			continue
+		}
+
+		readings++
+		if reading.Note != synth.A4I || math.Abs(float64(reading.Cents-15)) > 1 {
+			t.Fatalf("Expected A4 +15.0c, got %s.", reading)
+		}
+	}
+
+	// A reading is made 20 times a second, once the window has filled up.
+	if want := (testRate - tuner.WindowSize) / tuner.HopSize; readings < want {
+		t.Errorf("Expected at least %d readings, got %d.", want, readings)
+	}
+}
+
+func TestTunerSilence(t *testing.T) {
+	tuner := NewTuner(testRate)
+
+	for _, s := range makeSine(440, false) {
+		if reading, ok := tuner.AddFrame([]float32{s / 100}); ok {
+			t.Fatalf("Expected no reading for a quiet signal, got %s.", reading)
+		}
+	}
+}
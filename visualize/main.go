@@ -64,8 +64,9 @@ func destroy(window *glfw.Window, assets *Assets) error {
 	return nil
 }
 
-// Testing stuff to do with opening an OpenGL context.
-func Testing() error {
+// Testing stuff to do with opening an OpenGL context, drawing a sine wave at a
+// given sample rate.
+func Testing(sampleRate int) error {
 	window, assets, err := initialize()
 	if err != nil {
 		return nil
@@ -114,7 +115,7 @@ func Testing() error {
 		GetPastel(0),
 		false,
 		4.0,
-		DefaultGenerateSinePoints(440, phase))
+		DefaultGenerateSinePoints(440, phase, sampleRate))
 	defer lineRender2.Destroy()
 
 	gl.ClearColor(1.0, 1.0, 1.0, 1.0)
@@ -129,11 +130,11 @@ func Testing() error {
 		lineRender2.Render()
 
 		// Updating render for lineRender2.
-		phase += 2 * math.Pi * (440.0 / 44100.0)
+		phase += 2 * math.Pi * (440.0 / float32(sampleRate))
 		if phase > 2*math.Pi {
 			phase -= 2 * math.Pi
 		}
-		lineRender2.UpdatePoints(DefaultGenerateSinePoints(440, phase))
+		lineRender2.UpdatePoints(DefaultGenerateSinePoints(440, phase, sampleRate))
 
 		if config.DebugMode {
 			// Reporting OpenGL errors.
@@ -151,15 +152,51 @@ func Testing() error {
 	return nil
 }
 
-// Runs the visualization with a set of DelayedNoteData. The DelayedNote data is
-// used to push information to the synth as well as represent the data visually.
-func RunVisualization(notes *synth.NoteArrangement, oNoteChannel chan synth.DelayedNoteData) error {
+// The number of seconds the arrow keys move playback by.
+const seekStep float32 = 5
+
+// Handling the keys that control playback - space to pause and resume, and the
+// arrow keys to seek backwards and forwards.
+func controlPlayback(controller *synth.Controller) glfw.KeyCallback {
+	paused := false
+
+	return func(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+		if action == glfw.Release {
+			return
+		}
+
+		switch key {
+		case glfw.KeySpace:
+			if action != glfw.Press {
+				return
+			}
+
+			if paused {
+				controller.Resume()
+			} else {
+				controller.Pause()
+			}
+			paused = !paused
+		case glfw.KeyLeft:
+			controller.Seek(controller.Position() - seekStep)
+		case glfw.KeyRight:
+			controller.Seek(controller.Position() + seekStep)
+		}
+	}
+}
+
+// Runs the visualization with a set of DelayedNoteData. The notes are played
+// by the synth behind the controller, which the visualization uses to scrub
+// through them.
+func RunVisualization(notes *synth.NoteArrangement, controller *synth.Controller) error {
 	window, assets, err := initialize()
 	if err != nil {
 		return err
 	}
 	defer destroy(window, assets)
 
+	window.SetKeyCallback(controlPlayback(controller))
+
 	// The main update loop.
 	ct, lt, dt := 0.0, 0.0, 0.0
 	for !window.ShouldClose() {
@@ -29,6 +29,6 @@ func GenerateSinePoints(freq, initialPhase, fidelity float32, pointsLength, widt
 }
 
 // Only for testing purposes.
-func DefaultGenerateSinePoints(freq, initialPhase float32) []Point {
-	return GenerateSinePoints(freq, initialPhase, 44100, 640, 640)
+func DefaultGenerateSinePoints(freq, initialPhase float32, sampleRate int) []Point {
+	return GenerateSinePoints(freq, initialPhase, float32(sampleRate), 640, 640)
 }